@@ -10,6 +10,8 @@ type ClientAppConf struct {
 	ExpireRefreshHardcap int       `json:"expire_refresh_hardcap"`
 	MaxSessionsPerUser   int64     `json:"max_sessions_per_user"` // Max # of Service Sessions (Concurrent Connections) per User per ClientApp. 0 = unlimited
 	ExtAuthSecret        string    `json:"ext_auth_secret"`       // External Auth Service Secret // ToDo: make this map[string]string for multiple IdP's
+	DailyQuota           int64     `json:"daily_quota"`           // Max request cost per day. 0 = unlimited
+	MonthlyQuota         int64     `json:"monthly_quota"`         // Max request cost per month. 0 = unlimited
 	DebugOpts            DebugOpts `json:"debug_opts"`
 }
 
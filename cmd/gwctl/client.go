@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json/v2"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/logitools/gw/uds"
+)
+
+// client talks to the UDS admin socket in the JSON mode
+type client struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration // per request. 0 = none
+	nextID  int
+}
+
+// dial connects to the socket and switches the connection to the JSON mode
+func dial(socketPath string, timeout time.Duration) (*client, error) {
+	conn, err := net.DialTimeout("unix", socketPath, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	c := &client{conn: conn, reader: bufio.NewReader(conn), timeout: timeout}
+	if err = c.switchToJSON(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *client) switchToJSON() error {
+	c.setDeadline()
+	prompt := make([]byte, 2)
+	if _, err := io.ReadFull(c.reader, prompt); err != nil || string(prompt) != "> " {
+		return fmt.Errorf("unexpected greeting %q: %v", prompt, err)
+	}
+	if _, err := fmt.Fprintln(c.conn, uds.JSONModeCommand); err != nil {
+		return err
+	}
+	res, err := c.readResponse()
+	if err != nil {
+		return err
+	}
+	if res.Status != uds.StatusOK {
+		return fmt.Errorf("json mode refused: %s", res.Error)
+	}
+	return nil
+}
+
+// do runs a command and returns its response
+func (c *client) do(cmd string, args []string) (*uds.Response, error) {
+	c.nextID++
+	req := &uds.Request{ID: strconv.Itoa(c.nextID), Cmd: cmd, Args: args}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	c.setDeadline()
+	if _, err = c.conn.Write(append(data, '\n')); err != nil {
+		return nil, err
+	}
+	res, err := c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	if res.ID != req.ID {
+		return nil, fmt.Errorf("response id %q for request %q", res.ID, req.ID)
+	}
+	return res, nil
+}
+
+// commands returns the commands of the server for the completion
+func (c *client) commands() ([]uds.CommandInfo, error) {
+	res, err := c.do("help", nil)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(res.Data)
+	if err != nil {
+		return nil, err
+	}
+	var infos []uds.CommandInfo
+	if err = json.Unmarshal(data, &infos); err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+func (c *client) readResponse() (*uds.Response, error) {
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	var res uds.Response
+	if err = json.Unmarshal(line, &res); err != nil {
+		return nil, fmt.Errorf("invalid response %q: %w", line, err)
+	}
+	return &res, nil
+}
+
+func (c *client) setDeadline() {
+	if c.timeout > 0 {
+		_ = c.conn.SetDeadline(time.Now().Add(c.timeout))
+	} else {
+		_ = c.conn.SetDeadline(time.Time{})
+	}
+}
+
+func (c *client) close() {
+	_, _ = c.do("quit", nil)
+	_ = c.conn.Close()
+}
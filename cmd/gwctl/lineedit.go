@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	historyFile = ".gwctl_history" // in the home directory
+	historySize = 500
+)
+
+// Key Codes in the raw mode
+const (
+	keyCtrlA     = 1
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyBackspace = 8
+	keyTab       = 9
+	keyLF        = 10
+	keyCR        = 13
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// lineEditor reads lines from a terminal in the raw mode, with history (up/down) and tab completion of the command names.
+// The raw mode is set by stty(1), so that no terminal library is needed.
+type lineEditor struct {
+	in          *os.File
+	out         io.Writer
+	reader      *bufio.Reader
+	words       []string // completion candidates of the first word
+	history     []string
+	historyPath string
+}
+
+func newLineEditor(in *os.File, out io.Writer, words []string) *lineEditor {
+	e := &lineEditor{in: in, out: out, reader: bufio.NewReader(in), words: words}
+	if home, err := os.UserHomeDir(); err == nil {
+		e.historyPath = filepath.Join(home, historyFile)
+		if data, err := os.ReadFile(e.historyPath); err == nil {
+			for line := range strings.SplitSeq(string(data), "\n") {
+				if line != "" {
+					e.history = append(e.history, line)
+				}
+			}
+		}
+	}
+	return e
+}
+
+func (e *lineEditor) saveHistory() {
+	if e.historyPath == "" {
+		return
+	}
+	if len(e.history) > historySize {
+		e.history = e.history[len(e.history)-historySize:]
+	}
+	_ = os.WriteFile(e.historyPath, []byte(strings.Join(e.history, "\n")+"\n"), 0600)
+}
+
+// readLine reads a line after the prompt. io.EOF on Ctrl-D at an empty line
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	restore, err := e.rawMode()
+	if err != nil { // e.g. no stty: plain line input
+		_, _ = fmt.Fprint(e.out, prompt)
+		line, err := e.reader.ReadString('\n')
+		return strings.TrimRight(line, "\r\n"), err
+	}
+	defer restore()
+
+	var buf []rune
+	pos := 0
+	histIdx := len(e.history)
+	e.refresh(prompt, buf, pos)
+	for {
+		r, _, err := e.reader.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case keyCR, keyLF:
+			_, _ = fmt.Fprint(e.out, "\r\n")
+			line := strings.TrimSpace(string(buf))
+			if line != "" && (len(e.history) == 0 || e.history[len(e.history)-1] != line) {
+				e.history = append(e.history, line)
+			}
+			return line, nil
+		case keyCtrlC:
+			_, _ = fmt.Fprint(e.out, "^C\r\n")
+			buf, pos, histIdx = nil, 0, len(e.history)
+		case keyCtrlD:
+			if len(buf) == 0 {
+				_, _ = fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			if pos < len(buf) {
+				buf = slices.Delete(buf, pos, pos+1)
+			}
+		case keyBackspace, keyDelete:
+			if pos > 0 {
+				buf = slices.Delete(buf, pos-1, pos)
+				pos--
+			}
+		case keyCtrlA:
+			pos = 0
+		case keyCtrlE:
+			pos = len(buf)
+		case keyCtrlU:
+			buf, pos = buf[pos:], 0
+		case keyTab:
+			buf, pos = e.complete(prompt, buf, pos)
+		case keyEscape:
+			switch e.readEscape() {
+			case escUp:
+				if histIdx > 0 {
+					histIdx--
+					buf = []rune(e.history[histIdx])
+					pos = len(buf)
+				}
+			case escDown:
+				if histIdx < len(e.history) {
+					histIdx++
+					buf = nil
+					if histIdx < len(e.history) {
+						buf = []rune(e.history[histIdx])
+					}
+					pos = len(buf)
+				}
+			case escRight:
+				pos = min(pos+1, len(buf))
+			case escLeft:
+				pos = max(pos-1, 0)
+			case escHome:
+				pos = 0
+			case escEnd:
+				pos = len(buf)
+			case escDelete:
+				if pos < len(buf) {
+					buf = slices.Delete(buf, pos, pos+1)
+				}
+			}
+		default:
+			if r >= ' ' {
+				buf = slices.Insert(buf, pos, r)
+				pos++
+			}
+		}
+		e.refresh(prompt, buf, pos)
+	}
+}
+
+// Editing Keys decoded from the escape sequences
+const (
+	escNone = iota // unsupported or incomplete
+	escUp
+	escDown
+	escRight
+	escLeft
+	escHome
+	escEnd
+	escDelete
+)
+
+// readEscape reads the rest of an escape sequence after ESC and decodes it.
+// CSI sequences are ESC [ <parameter bytes 0x30-0x3F>... <intermediate bytes 0x20-0x2F>... <final byte 0x40-0x7E>,
+// e.g. ESC[A (up), ESC[3~ (delete), ESC[1;5C (ctrl+right). SS3 sequences are ESC O <final byte>, e.g. ESC OH (home).
+// Modifiers are ignored: ctrl+right moves like right
+func (e *lineEditor) readEscape() int {
+	b, err := e.reader.ReadByte()
+	if err != nil {
+		return escNone
+	}
+	if b == 'O' {
+		if b, err = e.reader.ReadByte(); err != nil {
+			return escNone
+		}
+		return escapeKey(b, "")
+	}
+	if b != '[' {
+		return escNone // e.g. alt+key
+	}
+	var params []byte
+	for {
+		if b, err = e.reader.ReadByte(); err != nil {
+			return escNone
+		}
+		switch {
+		case b >= 0x30 && b <= 0x3F:
+			params = append(params, b)
+		case b >= 0x20 && b <= 0x2F: // intermediate. none of the supported keys
+		case b >= 0x40 && b <= 0x7E:
+			return escapeKey(b, string(params))
+		default:
+			return escNone // malformed. drop it
+		}
+	}
+}
+
+// escapeKey maps the final byte and the parameters of an escape sequence to an editing key
+func escapeKey(final byte, params string) int {
+	switch final {
+	case 'A':
+		return escUp
+	case 'B':
+		return escDown
+	case 'C':
+		return escRight
+	case 'D':
+		return escLeft
+	case 'H':
+		return escHome
+	case 'F':
+		return escEnd
+	case '~':
+		key, _, _ := strings.Cut(params, ";") // the rest are modifiers
+		switch key {
+		case "1", "7":
+			return escHome
+		case "4", "8":
+			return escEnd
+		case "3":
+			return escDelete
+		}
+	}
+	return escNone
+}
+
+// complete completes the command name under the cursor: a single candidate in full, else their common prefix.
+// Without a common prefix to add, the candidates are listed
+func (e *lineEditor) complete(prompt string, buf []rune, pos int) ([]rune, int) {
+	prefix := string(buf[:pos])
+	if strings.ContainsRune(prefix, ' ') {
+		return buf, pos // only the command is completed
+	}
+	var candidates []string
+	for _, word := range e.words {
+		if strings.HasPrefix(word, prefix) {
+			candidates = append(candidates, word)
+		}
+	}
+	if len(candidates) == 0 {
+		return buf, pos
+	}
+	completion := candidates[0]
+	if len(candidates) == 1 {
+		completion += " "
+	} else {
+		for _, c := range candidates[1:] {
+			for !strings.HasPrefix(c, completion) {
+				completion = completion[:len(completion)-1]
+			}
+		}
+	}
+	if completion == prefix {
+		_, _ = fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+		return buf, pos
+	}
+	added := []rune(completion[len(prefix):])
+	return slices.Insert(buf, pos, added...), pos + len(added)
+}
+
+// refresh redraws the prompt and the line, and places the cursor
+func (e *lineEditor) refresh(prompt string, buf []rune, pos int) {
+	_, _ = fmt.Fprintf(e.out, "\r%s%s\x1b[K", prompt, string(buf))
+	if back := len(buf) - pos; back > 0 {
+		_, _ = fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+// rawMode switches the terminal to the raw mode without echo. Returns the function restoring the previous mode
+func (e *lineEditor) rawMode() (func(), error) {
+	saved, err := e.stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = e.stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() {
+		_, _ = e.stty(strings.TrimSpace(saved))
+	}, nil
+}
+
+func (e *lineEditor) stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = e.in
+	out, err := cmd.Output()
+	return string(out), err
+}
@@ -0,0 +1,207 @@
+// Command gwctl is a client of the UDS admin socket.
+//
+//	gwctl [flags] command [args...]   run one command. exit code 1 if it fails
+//	gwctl [flags]                     interactive mode with history and tab completion. reads the commands from stdin if not a terminal
+//
+// The socket is -socket, or else socket_path in <-root>/config/.uds.json. With -json, responses are printed as raw JSON lines.
+package main
+
+import (
+	"bufio"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/logitools/gw/uds"
+)
+
+// Exit Codes
+const (
+	exitOK         = 0
+	exitCmdFailed  = 1
+	exitConnFailed = 2
+)
+
+func main() {
+	os.Exit(run())
+}
+
+// run runs gwctl and returns the exit code
+func run() int {
+	socketPath := flag.String("socket", "", "path of the UDS admin socket. Default: socket_path in <root>/config/.uds.json")
+	appRoot := flag.String("root", ".", "app root to find config/.uds.json")
+	rawJSON := flag.Bool("json", false, "print the responses as raw JSON lines")
+	timeout := flag.Duration("timeout", 30*time.Second, "timeout per command. 0 = none")
+	flag.Usage = func() {
+		_, _ = fmt.Fprintln(flag.CommandLine.Output(), "usage: gwctl [flags] [command [args...]]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	path, err := resolveSocketPath(*socketPath, *appRoot)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "gwctl:", err)
+		return exitConnFailed
+	}
+	c, err := dial(path, *timeout)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "gwctl: cannot connect to %s: %v\n", path, err)
+		return exitConnFailed
+	}
+	defer c.close()
+
+	if flag.NArg() > 0 {
+		return runOne(c, flag.Arg(0), flag.Args()[1:], *rawJSON)
+	}
+	return runInteractive(c, *rawJSON)
+}
+
+func resolveSocketPath(socketPath string, appRoot string) (string, error) {
+	if socketPath != "" {
+		return socketPath, nil
+	}
+	confFilePath := filepath.Join(appRoot, "config", ".uds.json")
+	confBytes, err := os.ReadFile(confFilePath) // ([]byte, error)
+	if err != nil {
+		return "", fmt.Errorf("no -socket and %w", err)
+	}
+	var conf uds.Conf
+	if err = json.Unmarshal(confBytes, &conf); err != nil {
+		return "", fmt.Errorf("%s: %w", confFilePath, err)
+	}
+	if conf.SocketPath == "" {
+		return "", fmt.Errorf("%s: socket_path not set", confFilePath)
+	}
+	return conf.SocketPath, nil
+}
+
+// runOne runs a command and returns the exit code
+func runOne(c *client, cmd string, args []string, rawJSON bool) int {
+	res, err := c.do(cmd, args)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "gwctl:", err)
+		return exitConnFailed
+	}
+	printResponse(os.Stdout, os.Stderr, res, rawJSON)
+	if res.Status != uds.StatusOK {
+		return exitCmdFailed
+	}
+	return exitOK
+}
+
+// runInteractive runs the commands read from the terminal or stdin until quit or EOF.
+// Returns the exit code: exitCmdFailed if a command read from a non-terminal stdin failed
+func runInteractive(c *client, rawJSON bool) int {
+	infos, err := c.commands()
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "gwctl: cannot load the commands:", err)
+		return exitConnFailed
+	}
+	var readLine func() (string, error)
+	interactive := isTerminal(os.Stdin)
+	if interactive {
+		editor := newLineEditor(os.Stdin, os.Stdout, commandNames(infos))
+		defer editor.saveHistory()
+		readLine = func() (string, error) { return editor.readLine("> ") }
+	} else {
+		scanner := bufio.NewScanner(os.Stdin)
+		readLine = func() (string, error) {
+			if !scanner.Scan() {
+				if scanner.Err() != nil {
+					return "", scanner.Err()
+				}
+				return "", io.EOF
+			}
+			return scanner.Text(), nil
+		}
+	}
+	exitCode := exitOK
+	for {
+		line, err := readLine()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				_, _ = fmt.Fprintln(os.Stderr, "gwctl:", err)
+			}
+			return exitCode
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "q", "quit", "exit":
+			return exitCode
+		case "h", "help":
+			if !rawJSON {
+				printHelp(os.Stdout, infos)
+				continue
+			}
+		}
+		res, err := c.do(fields[0], fields[1:])
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, "gwctl:", err)
+			return exitConnFailed
+		}
+		printResponse(os.Stdout, os.Stderr, res, rawJSON)
+		if res.Status != uds.StatusOK && !interactive {
+			exitCode = exitCmdFailed
+		}
+	}
+}
+
+// printResponse prints the text output or the indented data, and the error to errW
+func printResponse(w io.Writer, errW io.Writer, res *uds.Response, rawJSON bool) {
+	if rawJSON {
+		data, _ := json.Marshal(res)
+		_, _ = fmt.Fprintf(w, "%s\n", data)
+		return
+	}
+	if res.Output != "" {
+		_, _ = fmt.Fprint(w, res.Output)
+		if !strings.HasSuffix(res.Output, "\n") {
+			_, _ = fmt.Fprintln(w)
+		}
+	}
+	if res.Data != nil {
+		data, err := json.Marshal(res.Data, jsontext.WithIndent("  "))
+		if err != nil {
+			_, _ = fmt.Fprintln(errW, "gwctl: cannot print the data:", err)
+		} else {
+			_, _ = fmt.Fprintf(w, "%s\n", data)
+		}
+	}
+	if res.Status != uds.StatusOK {
+		_, _ = fmt.Fprintf(errW, "ERROR> %s\n", res.Error)
+	}
+}
+
+func printHelp(w io.Writer, infos []uds.CommandInfo) {
+	group := ""
+	for _, info := range infos {
+		if info.Group != group {
+			group = info.Group
+			_, _ = fmt.Fprintf(w, "---- %s ----\n", group)
+		}
+		_, _ = fmt.Fprintf(w, "%-36s %s\n", info.Command, info.Desc)
+	}
+}
+
+func commandNames(infos []uds.CommandInfo) []string {
+	names := []string{"help", "quit"}
+	for _, info := range infos {
+		names = append(names, info.Command)
+	}
+	return names
+}
+
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
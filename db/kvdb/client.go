@@ -55,6 +55,20 @@ type Client interface {
 	// RemoveFields removes the specified fields in a hash key. Returns the number of fields actually removed.
 	RemoveFields(ctx context.Context, key string, fields ...string) (int64, error)
 	GetAllFields(ctx context.Context, key string) (map[string]string, error)
+
+	//---- Pub/Sub Ops ----
+
+	// Publish sends the message to the subscribers of the channel. Returns the number of subscribers that received it.
+	Publish(ctx context.Context, channel string, message string) (int64, error)
+
+	//---- Scripting Ops ----
+
+	// Eval runs the Lua script atomically with KEYS = keys and ARGV = args.
+	// Integer replies are int64, bulk strings are string, arrays are []any, and nil replies give ErrNil.
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
 }
 
 var ErrNotSupported = errors.New("kvdb: operation not supported")
+
+// ErrNil is returned by Eval when the script returns nil
+var ErrNil = errors.New("kvdb: nil reply")
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/logitools/gw/db/kvdb"
@@ -17,6 +18,7 @@ type Client struct {
 
 	// implementation details, not exported
 	internal *lowimpl.Client
+	scripts  sync.Map // script source -> *lowimpl.Script (EVALSHA with SHA1 cached)
 }
 
 // Ensure redis.Client implements kvdb.Client interface
@@ -201,3 +203,24 @@ func (c *Client) RemoveFields(ctx context.Context, key string, fields ...string)
 func (c *Client) GetAllFields(ctx context.Context, key string) (map[string]string, error) {
 	return c.internal.HGetAll(ctx, key).Result()
 }
+
+//---- Pub/Sub Ops ----
+
+func (c *Client) Publish(ctx context.Context, channel string, message string) (int64, error) {
+	return c.internal.Publish(ctx, channel, message).Result()
+}
+
+//---- Scripting Ops ----
+
+// Eval runs the script by EVALSHA, falling back to EVAL when the script is not cached on the server yet
+func (c *Client) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+	sAny, ok := c.scripts.Load(script)
+	if !ok {
+		sAny, _ = c.scripts.LoadOrStore(script, lowimpl.NewScript(script))
+	}
+	val, err := sAny.(*lowimpl.Script).Run(ctx, c.internal, keys, args...).Result()
+	if errors.Is(err, lowimpl.Nil) {
+		return nil, kvdb.ErrNil
+	}
+	return val, err
+}
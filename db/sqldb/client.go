@@ -12,6 +12,12 @@ type Client interface {
 	SinglePlaceholder(nth ...int) string       // n'th Placeholder (Optional, Default = 1)
 	Placeholders(cnt int, start ...int) string // Count, start (Optional, Default = 1)
 	RawSQLStore() *RawSQLStore
+	SetRawSQLStore(store *RawSQLStore) // [Hot Reload] swaps the store atomically
+
+	// QueryByKey runs the RawSQLStore statement of key as a cached prepared statement
+	QueryByKey(ctx context.Context, key string, args ...any) (Rows, error)
+	// ExecByKey runs the RawSQLStore statement of key as a cached prepared statement
+	ExecByKey(ctx context.Context, key string, args ...any) (Result, error)
 
 	Init() error
 	Open(ctx context.Context) error
@@ -9,4 +9,7 @@ type Conf struct {
 	DB   string `json:"db"`
 	TZ   string `json:"tz"`  // Connection Timezone
 	DSN  string `json:"dsn"` // To Overwrite Default DSN
+
+	NotificationTable string `json:"notification_table"` // [MySQL] Table for Listen/Notify emulation. Default: "sqldb_notifications"
+	StmtCacheSize     int    `json:"stmt_cache_size"`    // Max prepared statements cached for QueryByKey/ExecByKey (per connection for pgsql). Default: 256
 }
@@ -0,0 +1,241 @@
+package sqldb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/logitools/gw/model"
+	"github.com/logitools/gw/orm/coll"
+)
+
+// Relations is a registry of named relations between model pointer types for eager loading.
+// Register each relation once per model pair (e.g. at app init),
+// then resolve dotted paths like "Items.Product.Vendor" with EagerLoad.
+type Relations struct {
+	mu      sync.RWMutex
+	byOwner map[reflect.Type]map[string]*relation // owner model pointer type -> relation name -> relation
+}
+
+// relation is a type-erased loader
+// load receives a *coll.Collection of the owner models and returns a *coll.Collection of the related models
+type relation struct {
+	target reflect.Type // related model pointer type
+	load   func(ctx context.Context, dbClient Client, owners any) (any, error)
+}
+
+func NewRelations() *Relations {
+	return &Relations{byOwner: make(map[reflect.Type]map[string]*relation)}
+}
+
+func (r *Relations) set(owner reflect.Type, name string, rel *relation) error {
+	if name == "" || strings.Contains(name, ".") {
+		return fmt.Errorf("invalid relation name: %q", name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rels, ok := r.byOwner[owner]
+	if !ok {
+		rels = make(map[string]*relation)
+		r.byOwner[owner] = rels
+	}
+	if _, exists := rels[name]; exists {
+		return fmt.Errorf("relation %q already registered on %v", name, owner)
+	}
+	rels[name] = rel
+	return nil
+}
+
+func (r *Relations) get(owner reflect.Type, name string) (*relation, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rel, ok := r.byOwner[owner][name]
+	return rel, ok
+}
+
+// RegisterBelongsTo registers a Child-BelongsTo-Parent relation named `name` on the Child
+// It is loaded with LoadBelongsTo
+func RegisterBelongsTo[
+	CP model.Identifiable[CID],
+	CID comparable,
+	P any, // Model struct
+	PP ScannableIdentifiable[P, PID],
+	PID comparable,
+](
+	r *Relations,
+	name string,
+	sqlSelectBase string,
+	foreignKey func(c CP) PID, // on the child
+	relationFieldPtr func(c CP) *PP, // on the child
+) error {
+	return r.set(reflect.TypeFor[CP](), name, &relation{
+		target: reflect.TypeFor[PP](),
+		load: func(ctx context.Context, dbClient Client, owners any) (any, error) {
+			children := owners.(*coll.Collection[CP, CID])
+			if children.Len() == 0 {
+				return coll.NewEmptyOrderedCollection[PP, PID](), nil
+			}
+			return LoadBelongsTo[CP, CID, P, PP, PID](ctx, dbClient, children, sqlSelectBase, foreignKey, relationFieldPtr)
+		},
+	})
+}
+
+// RegisterHasMany registers a Parent-HasMany-Children relation named `name` on the Parent
+// It is loaded with LoadHasMany
+func RegisterHasMany[
+	PP model.Identifiable[PID],
+	PID comparable,
+	C any, // Model struct
+	CP ScannableIdentifiable[C, CID],
+	CID comparable,
+](
+	r *Relations,
+	name string,
+	sqlSelectBase string,
+	foreignKeyColumn Column, // on the child
+	foreignKey func(CP) PID, // on the child
+	relationFieldPtr func(PP) **coll.Collection[CP, CID], // on the parent
+	orderBys ...OrderBy,
+) error {
+	return r.set(reflect.TypeFor[PP](), name, &relation{
+		target: reflect.TypeFor[CP](),
+		load: func(ctx context.Context, dbClient Client, owners any) (any, error) {
+			parents := owners.(*coll.Collection[PP, PID])
+			if parents.Len() == 0 {
+				return coll.NewEmptyOrderedCollection[CP, CID](), nil
+			}
+			return LoadHasMany[PP, PID, C, CP, CID](ctx, dbClient, parents, sqlSelectBase, foreignKeyColumn, foreignKey, relationFieldPtr, orderBys...)
+		},
+	})
+}
+
+// RegisterManyToMany registers a Parent-ManyToMany-Children relation named `name` on the Parent
+// It is loaded with LoadManyToMany
+func RegisterManyToMany[
+	PP model.Identifiable[PID],
+	PID comparable,
+	V any, // Pivot Model struct
+	VP Scannable[V],
+	C any, // Model struct
+	CP ScannableIdentifiable[C, CID],
+	CID comparable,
+](
+	r *Relations,
+	name string,
+	pivotSelectBase string,
+	pivotParentKeyColumn Column, // on the pivot
+	pivotKeys func(VP) (PID, CID), // on the pivot
+	sqlSelectBase string,
+	relationFieldPtr func(PP) **coll.Collection[CP, CID], // on the parent
+	pivotFieldPtr func(PP) *map[CID]VP, // on the parent. optional
+	orderBys ...OrderBy,
+) error {
+	return r.set(reflect.TypeFor[PP](), name, &relation{
+		target: reflect.TypeFor[CP](),
+		load: func(ctx context.Context, dbClient Client, owners any) (any, error) {
+			parents := owners.(*coll.Collection[PP, PID])
+			children, _, err := LoadManyToMany[PP, PID, V, VP, C, CP, CID](
+				ctx, dbClient, parents,
+				pivotSelectBase, pivotParentKeyColumn, pivotKeys,
+				sqlSelectBase, relationFieldPtr, pivotFieldPtr,
+				orderBys...,
+			)
+			return children, err
+		},
+	})
+}
+
+// eagerNode is a node of the path tree built from the eager load paths
+// "Items.Product.Vendor" and "Items.Discounts" share the "Items" node, so Items are loaded only once
+type eagerNode struct {
+	name     string
+	children []*eagerNode // keeps the order of the given paths
+}
+
+func (n *eagerNode) child(name string) *eagerNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	c := &eagerNode{name: name}
+	n.children = append(n.children, c)
+	return c
+}
+
+func buildEagerTree(paths []string) (*eagerNode, error) {
+	root := &eagerNode{}
+	for _, path := range paths {
+		node := root
+		for _, name := range strings.Split(path, ".") {
+			if name == "" {
+				return nil, fmt.Errorf("invalid eager load path: %q", path)
+			}
+			node = node.child(name)
+		}
+	}
+	return root, nil
+}
+
+// EagerLoad resolves the relation paths (e.g. "Items.Product.Vendor") from the root models
+// and links all the related models into the relation fields.
+// Each distinct path prefix is loaded with one batched query (two for many-to-many) regardless of the number of models.
+func EagerLoad[
+	MP model.Identifiable[ID],
+	ID comparable,
+](
+	ctx context.Context,
+	dbClient Client,
+	rels *Relations,
+	roots *coll.Collection[MP, ID],
+	paths ...string,
+) error {
+	tree, err := buildEagerTree(paths)
+	if err != nil {
+		return err
+	}
+	return rels.loadNode(ctx, dbClient, tree, reflect.TypeFor[MP](), roots, "")
+}
+
+func (r *Relations) loadNode(ctx context.Context, dbClient Client, node *eagerNode, ownerType reflect.Type, owners any, prefix string) error {
+	for _, childNode := range node.children {
+		path := prefix + childNode.name
+		rel, ok := r.get(ownerType, childNode.name)
+		if !ok {
+			return fmt.Errorf("eager load %q: relation %q not registered on %v", path, childNode.name, ownerType)
+		}
+		related, err := rel.load(ctx, dbClient, owners)
+		if err != nil {
+			return fmt.Errorf("eager load %q: %w", path, err)
+		}
+		if err = r.loadNode(ctx, dbClient, childNode, rel.target, related, path+"."); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RawQueryCollectionEager queries items using rawSQLStmt into a collection, then eager-loads the relation paths on it
+func RawQueryCollectionEager[
+	M any, // Model struct
+	MP ScannableIdentifiable[M, ID], // *Model implementing ScannableIdentifiable[M, ID]
+	ID comparable,
+](
+	ctx context.Context,
+	dbClient Client,
+	rels *Relations,
+	paths []string,
+	rawSQLStmt string,
+	args ...any, // variadic
+) (*coll.Collection[MP, ID], error) {
+	c, err := RawQueryCollection[M, MP, ID](ctx, dbClient, rawSQLStmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err = EagerLoad[MP, ID](ctx, dbClient, rels, c, paths...); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
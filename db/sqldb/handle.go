@@ -13,7 +13,11 @@ type Handle interface {
 	// You stream all the rows in one operation
 	CopyFrom(ctx context.Context, table string, columns []string, rows [][]any) (int64, error)
 
-	Listen(ctx context.Context, channel string) (<-chan Notification, error)
+	// Listen subscribes to the channels on a single connection until ctx is done.
+	// The returned channel is closed when the subscription ends.
+	Listen(ctx context.Context, channels ...string) (<-chan Notification, error)
+	// Notify sends a notification with the payload to the listeners of the channel
+	Notify(ctx context.Context, channel string, payload string) error
 	Prepare(ctx context.Context, query string) (PreparedStmt, error)
 
 	// InsertStmt - Single INSERT statement, placeholders only
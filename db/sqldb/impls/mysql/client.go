@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql" // side-effect
@@ -16,13 +18,19 @@ type Client struct {
 	Handle // [Embedded] for Promoted Methods
 	conf   *sqldb.Conf
 	dsn    string
+
+	rawStore atomic.Pointer[sqldb.RawSQLStore] // [Hot Reload] SetRawSQLStore
+
+	stmtMu    sync.Mutex
+	stmtCache *sqldb.StmtCache[*sql.Stmt] // for QueryByKey, ExecByKey
 }
 
 // Ensure mysql.Client implements sqldb.Client interface
 var _ sqldb.Client = (*Client)(nil)
 
 func NewClient(conf *sqldb.Conf) (sqldb.Client, error) {
-	return &Client{conf: conf}, nil
+	c := &Client{conf: conf}
+	return c, nil
 }
 
 func (c *Client) Init() error {
@@ -39,6 +47,14 @@ func (c *Client) Init() error {
 			c.conf.TZ,
 		)
 	}
+	c.notificationTable = c.conf.NotificationTable
+	if c.notificationTable == "" {
+		c.notificationTable = DefaultNotificationTable
+	}
+	if !sqldb.IdentifierRegexp.MatchString(c.notificationTable) {
+		return fmt.Errorf("invalid notification table: %q", c.notificationTable)
+	}
+	c.stmtCache = sqldb.NewStmtCache[*sql.Stmt](c.conf.StmtCacheSize)
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	// Open
@@ -55,7 +71,7 @@ func (c *Client) Init() error {
 }
 
 func (c *Client) DBHandle() sqldb.Handle {
-	return &Handle{DB: c.DB}
+	return &Handle{DB: c.DB, notificationTable: c.notificationTable}
 }
 
 func (c *Client) Conf() *sqldb.Conf {
@@ -80,8 +96,18 @@ func (c *Client) Placeholders(cnt int, _ ...int) string {
 	return strings.Join(placeholders, ",")
 }
 
+// RawSQLStore returns the store set by SetRawSQLStore, else the one of LoadRawStmtsToStore
 func (c *Client) RawSQLStore() *sqldb.RawSQLStore {
-	return rawStmtStore
+	if store := c.rawStore.Load(); store != nil {
+		return store
+	}
+	return rawStmtStore.Load()
+}
+
+// SetRawSQLStore swaps the RawSQLStore atomically
+// Cached prepared statements of changed SQL are prepared again on their next use
+func (c *Client) SetRawSQLStore(store *sqldb.RawSQLStore) {
+	c.rawStore.Store(store)
 }
 
 func (c *Client) Open(_ context.Context) error {
@@ -101,6 +127,9 @@ func (c *Client) Close() error {
 		return nil
 	}
 	log.Println("[INFO] closing mysql client")
+	c.stmtMu.Lock()
+	closeStmts(c.stmtCache.Clear()...)
+	c.stmtMu.Unlock()
 	err := c.DB.Close()
 	if err != nil {
 		return err
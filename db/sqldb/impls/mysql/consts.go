@@ -1,5 +1,13 @@
 package mysql
 
+import "time"
+
 const DBType = "mysql"
 const DefaultPlaceholderPrefix byte = '?'
 const DefaultSinglePlaceholder = "?"
+
+const DefaultNotificationTable = "sqldb_notifications"
+const NotificationPollInterval = time.Second
+const notificationPollLimit = 1000
+const NotificationGapWait = 30 * time.Second // how long a skipped id may stay uncommitted before a gap is reported
+const notificationMaxPending = 1000          // skipped ids tracked at most. beyond it, a gap is reported at once
@@ -10,7 +10,8 @@ import (
 )
 
 type Handle struct {
-	*sql.DB // [Embedded]
+	*sql.DB           // [Embedded]
+	notificationTable string
 }
 
 // Ensure mysql.Handle implements sqldb.Handle interface
@@ -46,11 +47,6 @@ func (h *Handle) CopyFrom(_ context.Context, _ string, _ []string, _ [][]any) (i
 	return 0, fmt.Errorf("method `CopyFrom` not supported for MySQL")
 }
 
-// Listen - param: channel
-func (h *Handle) Listen(_ context.Context, _ string) (<-chan sqldb.Notification, error) {
-	return nil, fmt.Errorf("method `Listen` not supported for MySQL")
-}
-
 func (h *Handle) InsertStmt(ctx context.Context, query string, args ...any) (sqldb.Result, error) {
 	trimmed := strings.TrimSpace(query)
 	if !strings.HasPrefix(strings.ToUpper(trimmed), "INSERT") {
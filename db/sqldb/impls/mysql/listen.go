@@ -0,0 +1,190 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/logitools/gw/db/sqldb"
+)
+
+// Listen emulates LISTEN by polling the notification table (Conf.NotificationTable) every NotificationPollInterval.
+// Only the notifications inserted after Listen is called are delivered.
+// Since rows stay in the table, polling errors do not lose notifications.
+// Auto-increment ids are not committed in order: an id skipped by a poll may belong to a transaction still running.
+// Skipped ids are polled again until they show up, and after NotificationGapWait a Notification{Gap: true} is sent,
+// since they may also have been rolled back. The notifications of a late commit are delivered out of id order.
+//
+//	CREATE TABLE sqldb_notifications (
+//	  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+//	  channel VARCHAR(64) NOT NULL,
+//	  payload TEXT NOT NULL,
+//	  pid INT UNSIGNED NOT NULL DEFAULT 0,
+//	  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+//	  KEY idx_created_at (created_at)
+//	);
+//
+// Old rows are never deleted here. Purge them periodically. e.g. with a cron job
+func (h *Handle) Listen(ctx context.Context, channels ...string) (<-chan sqldb.Notification, error) {
+	if len(channels) == 0 {
+		return nil, errors.New("no channels to listen")
+	}
+	p := &notificationPoller{
+		h:        h,
+		channels: make(map[string]struct{}, len(channels)),
+		pending:  make(map[uint64]time.Time),
+	}
+	err := h.DB.QueryRowContext(ctx, fmt.Sprintf("SELECT COALESCE(MAX(id), 0) FROM %s", h.notificationTable)).Scan(&p.lastID)
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range channels {
+		p.channels[channel] = struct{}{}
+		p.channelArgs = append(p.channelArgs, channel)
+	}
+	// every row is read to find the skipped ids, but only the payloads of the channels
+	p.selectBase = fmt.Sprintf(
+		"SELECT id, channel, CASE WHEN channel IN (%s) THEN payload ELSE '' END, pid FROM %s WHERE ",
+		strings.Join(sqldb.PlaceholdersGF(DefaultPlaceholderPrefix)(len(channels)), ","),
+		h.notificationTable,
+	)
+
+	notifyCh := make(chan sqldb.Notification)
+
+	go func() {
+		defer close(notifyCh)
+		ticker := time.NewTicker(NotificationPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			notifications, err := p.poll(ctx, time.Now())
+			for _, n := range notifications {
+				select {
+				case notifyCh <- n:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Printf("[WARN][%s] polling notifications on %v failed: %v", DBType, channels, err)
+			}
+		}
+	}()
+
+	return notifyCh, nil
+}
+
+// notificationPoller keeps the polling state of a Listen
+type notificationPoller struct {
+	h           *Handle
+	channels    map[string]struct{}
+	channelArgs []any
+	selectBase  string
+	lastID      uint64               // highest id read
+	pending     map[uint64]time.Time // ids below lastID not read yet -> when skipped
+}
+
+// poll reads the rows committed since the last poll, and the skipped ids committed late.
+// Returns the notifications of the channels, followed by a gap notification if skipped ids were given up.
+// On error, the notifications read so far are returned with it
+func (p *notificationPoller) poll(ctx context.Context, now time.Time) ([]sqldb.Notification, error) {
+	var notifications []sqldb.Notification
+	gap := false
+	if len(p.pending) > 0 {
+		ids := make([]any, 0, len(p.pending))
+		for id := range p.pending {
+			ids = append(ids, id)
+		}
+		rows, err := p.query(ctx, fmt.Sprintf("id IN (%s)", strings.Join(sqldb.PlaceholdersGF(DefaultPlaceholderPrefix)(len(ids)), ",")), ids...)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range rows {
+			delete(p.pending, n.id)
+			notifications = p.appendMatched(notifications, n)
+		}
+	}
+	for {
+		rows, err := p.query(ctx, fmt.Sprintf("id > ? ORDER BY id LIMIT %d", notificationPollLimit), p.lastID)
+		if err != nil {
+			return notifications, err
+		}
+		for _, n := range rows {
+			if n.id-p.lastID-1 > notificationMaxPending-uint64(len(p.pending)) {
+				gap = true // too many to track
+			} else {
+				for id := p.lastID + 1; id < n.id; id++ {
+					p.pending[id] = now
+				}
+			}
+			p.lastID = n.id
+			notifications = p.appendMatched(notifications, n)
+		}
+		if len(rows) < notificationPollLimit {
+			break
+		}
+		// more rows pending. poll again without waiting
+	}
+	for id, skippedAt := range p.pending {
+		if now.Sub(skippedAt) >= NotificationGapWait {
+			delete(p.pending, id)
+			gap = true
+		}
+	}
+	if gap {
+		notifications = append(notifications, sqldb.Notification{Gap: true})
+	}
+	return notifications, nil
+}
+
+func (p *notificationPoller) appendMatched(notifications []sqldb.Notification, n polledNotification) []sqldb.Notification {
+	if _, ok := p.channels[n.Channel]; ok {
+		return append(notifications, n.Notification)
+	}
+	return notifications
+}
+
+type polledNotification struct {
+	sqldb.Notification
+	id uint64
+}
+
+func (p *notificationPoller) query(ctx context.Context, where string, args ...any) ([]polledNotification, error) {
+	rows, err := p.h.DB.QueryContext(ctx, p.selectBase+where, append(append([]any(nil), p.channelArgs...), args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("rows.Close() failed: %v", err)
+		}
+	}()
+	var notifications []polledNotification
+	for rows.Next() {
+		var n polledNotification
+		if err = rows.Scan(&n.id, &n.Channel, &n.Payload, &n.PID); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// Notify inserts a notification row to be picked up by the pollers. PID = sender's connection id
+func (h *Handle) Notify(ctx context.Context, channel string, payload string) error {
+	_, err := h.DB.ExecContext(ctx,
+		fmt.Sprintf("INSERT INTO %s (channel, payload, pid) VALUES (?, ?, CONNECTION_ID())", h.notificationTable),
+		channel, payload,
+	)
+	return err
+}
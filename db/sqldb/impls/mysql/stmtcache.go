@@ -0,0 +1,121 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/logitools/gw/db/sqldb"
+)
+
+// erNeedReprepare - ER_NEED_REPREPARE: "Prepared statement needs to be re-prepared" after a schema change
+const erNeedReprepare = 1615
+
+// isStmtInvalidErr reports whether the cached *sql.Stmt has to be prepared again.
+// "sql: statement is closed" happens when the statement was evicted by another goroutine in between.
+func isStmtInvalidErr(err error) bool {
+	var myErr *mysqldriver.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == erNeedReprepare
+	}
+	return strings.Contains(err.Error(), "statement is closed")
+}
+
+func closeStmts(stmts ...*sql.Stmt) {
+	for _, stmt := range stmts {
+		if err := stmt.Close(); err != nil {
+			log.Printf("[WARN][SQLDB] closing prepared statement failed: %v", err)
+		}
+	}
+}
+
+// cachedStmt returns the *sql.Stmt prepared for key, preparing it on a miss.
+// A *sql.Stmt is safe for concurrent use and re-prepares itself on each connection of the *sql.DB as needed.
+func (c *Client) cachedStmt(ctx context.Context, key string, query string) (*sql.Stmt, error) {
+	c.stmtMu.Lock()
+	stmt, ok := c.stmtCache.Get(key, query)
+	c.stmtMu.Unlock()
+	if ok {
+		return stmt, nil
+	}
+	stmt, err := c.DB.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	c.stmtMu.Lock()
+	if existing, ok := c.stmtCache.Get(key, query); ok {
+		// prepared concurrently by another goroutine
+		c.stmtMu.Unlock()
+		closeStmts(stmt)
+		return existing, nil
+	}
+	dropped := c.stmtCache.Put(key, query, stmt)
+	c.stmtMu.Unlock()
+	closeStmts(dropped...)
+	return stmt, nil
+}
+
+// invalidate drops the statement of key
+func (c *Client) invalidate(key string) {
+	c.stmtMu.Lock()
+	stmt, ok := c.stmtCache.Remove(key)
+	c.stmtMu.Unlock()
+	if ok {
+		closeStmts(stmt)
+	}
+}
+
+// QueryByKey runs the RawSQLStore statement of key as a cached prepared statement.
+// A statement invalidated by a schema change is prepared again and retried once.
+func (c *Client) QueryByKey(ctx context.Context, key string, args ...any) (sqldb.Rows, error) {
+	query, ok := c.RawSQLStore().Get(key)
+	if !ok {
+		return nil, &sqldb.ErrRawSQLKeyNotFound{Key: key}
+	}
+	var rows *sql.Rows
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var stmt *sql.Stmt
+		if stmt, err = c.cachedStmt(ctx, key, query); err != nil {
+			return nil, err
+		}
+		rows, err = stmt.QueryContext(ctx, args...)
+		if err == nil || !isStmtInvalidErr(err) {
+			break
+		}
+		c.invalidate(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Rows{rows: rows}, nil
+}
+
+// ExecByKey runs the RawSQLStore statement of key as a cached prepared statement.
+// A statement invalidated by a schema change is prepared again and retried once.
+func (c *Client) ExecByKey(ctx context.Context, key string, args ...any) (sqldb.Result, error) {
+	query, ok := c.RawSQLStore().Get(key)
+	if !ok {
+		return nil, &sqldb.ErrRawSQLKeyNotFound{Key: key}
+	}
+	var result sql.Result
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var stmt *sql.Stmt
+		if stmt, err = c.cachedStmt(ctx, key, query); err != nil {
+			return nil, err
+		}
+		result, err = stmt.ExecContext(ctx, args...)
+		if err == nil || !isStmtInvalidErr(err) {
+			break
+		}
+		c.invalidate(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Result{result: result}, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -15,13 +16,18 @@ type Client struct {
 	Handle // [Embedded] for Promoted Methods
 	conf   *sqldb.Conf
 	dsn    string
+
+	rawStore atomic.Pointer[sqldb.RawSQLStore] // [Hot Reload] SetRawSQLStore
+
+	stmtCaches *connStmtCaches // for QueryByKey, ExecByKey
 }
 
 // Ensure pgsql.Client implements sqldb.Client interface
 var _ sqldb.Client = (*Client)(nil)
 
 func NewClient(conf *sqldb.Conf) (sqldb.Client, error) {
-	return &Client{conf: conf}, nil
+	c := &Client{conf: conf}
+	return c, nil
 }
 
 func (c *Client) Init() error {
@@ -92,8 +98,18 @@ func (c *Client) Placeholders(cnt int, start ...int) string {
 	return strings.Join(placeholders, ",")
 }
 
+// RawSQLStore returns the store set by SetRawSQLStore, else the one of LoadRawStmtsToStore
 func (c *Client) RawSQLStore() *sqldb.RawSQLStore {
-	return rawStmtStore
+	if store := c.rawStore.Load(); store != nil {
+		return store
+	}
+	return rawStmtStore.Load()
+}
+
+// SetRawSQLStore swaps the RawSQLStore atomically
+// Cached prepared statements of changed SQL are prepared again on their next use
+func (c *Client) SetRawSQLStore(store *sqldb.RawSQLStore) {
+	c.rawStore.Store(store)
 }
 
 func (c *Client) Open(ctx context.Context) error {
@@ -105,6 +121,8 @@ func (c *Client) Open(ctx context.Context) error {
 	config.MaxConns = 10
 	config.MinConns = 2
 	config.MaxConnLifetime = 3 * time.Minute
+	c.stmtCaches = newConnStmtCaches(c.conf.StmtCacheSize)
+	config.BeforeClose = c.stmtCaches.drop
 	c.Pool, err = pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		return fmt.Errorf("failed to connect pgx Pool: %w", err)
@@ -3,9 +3,7 @@ package pgsql
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
-	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -52,45 +50,6 @@ func (h *Handle) CopyFrom(ctx context.Context, table string, columns []string, r
 	return count, err
 }
 
-func (h *Handle) Listen(ctx context.Context, channel string) (<-chan sqldb.Notification, error) {
-	conn, err := h.Pool.Acquire(ctx)
-	// NOTE: We can process a DBMS-specific error to produce a better abstracted error
-	if err != nil {
-		return nil, err
-	}
-
-	notifyCh := make(chan sqldb.Notification)
-
-	go func() {
-		defer conn.Release()
-		defer close(notifyCh)
-
-		_, err := conn.Exec(ctx, fmt.Sprintf("LISTEN %s;", pgx.Identifier{channel}.Sanitize()))
-		if err != nil {
-			log.Printf("[WARN] failed to LISTEN on %s: %v", channel, err)
-			return
-		}
-
-		for {
-			notification, err := conn.Conn().WaitForNotification(ctx)
-			if err != nil {
-				log.Printf("[WARN] Listen loop ended for %s: %v", channel, err)
-				return
-			}
-			select {
-			case notifyCh <- sqldb.Notification{
-				Channel: notification.Channel,
-				Payload: notification.Payload,
-			}:
-			case <-ctx.Done():
-				return
-			}
-		}
-	}()
-
-	return notifyCh, nil
-}
-
 func (h *Handle) InsertStmt(ctx context.Context, query string, args ...any) (sqldb.Result, error) {
 	trimmed := strings.TrimSpace(query)
 	if !strings.HasPrefix(strings.ToUpper(trimmed), "INSERT") {
@@ -118,7 +77,7 @@ func (h *Handle) Prepare(ctx context.Context, query string) (sqldb.PreparedStmt,
 	if err != nil {
 		return nil, err
 	}
-	stmtName := fmt.Sprintf("stmt_%x", time.Now().UnixNano())
+	stmtName := nextStmtName()
 	_, err = conn.Conn().Prepare(ctx, stmtName, query)
 	if err != nil {
 		conn.Release()
@@ -0,0 +1,112 @@
+package pgsql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/logitools/gw/db/sqldb"
+)
+
+const (
+	listenMinBackoff = 500 * time.Millisecond
+	listenMaxBackoff = 30 * time.Second
+)
+
+// Listen runs LISTEN for all the channels on a dedicated connection (not from the Pool).
+// If the connection is lost, it reconnects with exponential backoff, re-establishes LISTEN
+// and sends a Notification{Gap: true} since notifications in between are lost.
+// The returned channel is closed when ctx is done.
+func (h *Handle) Listen(ctx context.Context, channels ...string) (<-chan sqldb.Notification, error) {
+	if len(channels) == 0 {
+		return nil, errors.New("no channels to listen")
+	}
+	connConfig := h.Pool.Config().ConnConfig.Copy()
+	// Fail upfront on the first connection
+	conn, err := connectAndListen(ctx, connConfig, channels)
+	if err != nil {
+		return nil, err
+	}
+
+	notifyCh := make(chan sqldb.Notification)
+
+	go func() {
+		defer close(notifyCh)
+		for {
+			err := waitForNotifications(ctx, conn, notifyCh)
+			closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			_ = conn.Close(closeCtx)
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("[WARN][%s] listen connection lost on %v: %v", DBType, channels, err)
+			// Reconnect
+			backoff := listenMinBackoff
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				conn, err = connectAndListen(ctx, connConfig, channels)
+				if err == nil {
+					break
+				}
+				log.Printf("[WARN][%s] re-listen on %v failed (retry in %v): %v", DBType, channels, backoff, err)
+				backoff = min(backoff*2, listenMaxBackoff)
+			}
+			log.Printf("[INFO][%s] listening again on %v", DBType, channels)
+			// Report the gap
+			select {
+			case notifyCh <- sqldb.Notification{Gap: true}:
+			case <-ctx.Done():
+				_ = conn.Close(context.Background())
+				return
+			}
+		}
+	}()
+
+	return notifyCh, nil
+}
+
+func connectAndListen(ctx context.Context, connConfig *pgx.ConnConfig, channels []string) (*pgx.Conn, error) {
+	conn, err := pgx.ConnectConfig(ctx, connConfig)
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range channels {
+		if _, err = conn.Exec(ctx, fmt.Sprintf("LISTEN %s;", pgx.Identifier{channel}.Sanitize())); err != nil {
+			_ = conn.Close(ctx)
+			return nil, fmt.Errorf("failed to LISTEN on %s: %w", channel, err)
+		}
+	}
+	return conn, nil
+}
+
+// waitForNotifications relays notifications until ctx is done or the connection fails
+func waitForNotifications(ctx context.Context, conn *pgx.Conn, notifyCh chan<- sqldb.Notification) error {
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		select {
+		case notifyCh <- sqldb.Notification{
+			PID:     notification.PID,
+			Channel: notification.Channel,
+			Payload: notification.Payload,
+		}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (h *Handle) Notify(ctx context.Context, channel string, payload string) error {
+	_, err := h.Pool.Exec(ctx, "SELECT pg_notify($1, $2)", channel, payload)
+	return err
+}
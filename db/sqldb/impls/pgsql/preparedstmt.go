@@ -31,7 +31,9 @@ func (p *PreparedStmt) Exec(ctx context.Context, args ...any) (sqldb.Result, err
 	return &Result{tag: tag}, nil
 }
 
+// Close deallocates the statement and releases the pinned connection back to the Pool
 func (p *PreparedStmt) Close() error {
+	deallocate(p.conn, p.stmtName)
 	p.conn.Release()
 	return nil
 }
@@ -10,6 +10,7 @@ type Rows struct {
 	conn    *pgxpool.Conn
 	current pgx.Rows
 	batch   pgx.BatchResults
+	onClose func(err error) // receives the rows error before the connection is released
 }
 
 // Ensure pgsql.Rows implements sqldb.Rows
@@ -44,6 +45,9 @@ func (r *Rows) Scan(dest ...any) error {
 func (r *Rows) Close() error {
 	if r.current != nil {
 		r.current.Close()
+		if r.onClose != nil {
+			r.onClose(r.current.Err())
+		}
 	}
 	if r.batch != nil {
 		_ = r.batch.Close()
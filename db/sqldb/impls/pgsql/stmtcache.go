@@ -0,0 +1,160 @@
+package pgsql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/logitools/gw/db/sqldb"
+)
+
+// stmtSeq numbers the prepared statement names. Names must be unique per connection
+var stmtSeq atomic.Uint64
+
+func nextStmtName() string {
+	return fmt.Sprintf("gw_stmt_%d", stmtSeq.Add(1))
+}
+
+// connStmtCaches holds the cached prepared statement names of each pooled connection.
+// A connection's cache is only used by whoever acquired the connection, so the cache itself needs no lock.
+type connStmtCaches struct {
+	mu     sync.Mutex
+	size   int
+	byConn map[*pgx.Conn]*sqldb.StmtCache[string]
+}
+
+func newConnStmtCaches(size int) *connStmtCaches {
+	return &connStmtCaches{size: size, byConn: make(map[*pgx.Conn]*sqldb.StmtCache[string])}
+}
+
+func (cc *connStmtCaches) get(conn *pgx.Conn) *sqldb.StmtCache[string] {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cache, ok := cc.byConn[conn]
+	if !ok {
+		cache = sqldb.NewStmtCache[string](cc.size)
+		cc.byConn[conn] = cache
+	}
+	return cache
+}
+
+// drop forgets the cache of a closing connection. Its statements die with the connection.
+func (cc *connStmtCaches) drop(conn *pgx.Conn) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	delete(cc.byConn, conn)
+}
+
+// isStmtInvalidErr reports whether the prepared statement has to be prepared again
+// e.g. "cached plan must not change result type" after ALTER TABLE, or the statement is gone after DISCARD ALL
+func isStmtInvalidErr(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return (pgErr.Code == "0A000" && strings.Contains(pgErr.Message, "cached plan")) || pgErr.Code == "26000"
+}
+
+func deallocate(conn *pgxpool.Conn, names ...string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, name := range names {
+		if err := conn.Conn().Deallocate(ctx, name); err != nil {
+			log.Printf("[WARN][SQLDB] deallocate %s failed: %v", name, err)
+		}
+	}
+}
+
+// preparedName returns the name of the statement prepared for key on conn, preparing it on a miss
+func (c *Client) preparedName(ctx context.Context, conn *pgxpool.Conn, key string, sql string) (string, error) {
+	cache := c.stmtCaches.get(conn.Conn())
+	if name, ok := cache.Get(key, sql); ok {
+		return name, nil
+	}
+	name := nextStmtName()
+	if _, err := conn.Conn().Prepare(ctx, name, sql); err != nil {
+		return "", err
+	}
+	deallocate(conn, cache.Put(key, sql, name)...)
+	return name, nil
+}
+
+// invalidate drops the statement of key on conn
+func (c *Client) invalidate(conn *pgxpool.Conn, key string) {
+	if name, ok := c.stmtCaches.get(conn.Conn()).Remove(key); ok {
+		deallocate(conn, name)
+	}
+}
+
+// QueryByKey runs the RawSQLStore statement of key, prepared once per connection.
+// A statement invalidated by a schema change fails the query once, then is prepared again on the next call.
+func (c *Client) QueryByKey(ctx context.Context, key string, args ...any) (sqldb.Rows, error) {
+	sql, ok := c.RawSQLStore().Get(key)
+	if !ok {
+		return nil, &sqldb.ErrRawSQLKeyNotFound{Key: key}
+	}
+	conn, err := c.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	name, err := c.preparedName(ctx, conn, key, sql)
+	if err != nil {
+		conn.Release()
+		return nil, err
+	}
+	rows, err := conn.Query(ctx, name, args...)
+	if err != nil {
+		if isStmtInvalidErr(err) {
+			c.invalidate(conn, key)
+		}
+		conn.Release()
+		return nil, err
+	}
+	return &Rows{
+		conn:    conn, // released on Close
+		current: rows,
+		onClose: func(err error) {
+			if isStmtInvalidErr(err) {
+				c.invalidate(conn, key)
+			}
+		},
+	}, nil
+}
+
+// ExecByKey runs the RawSQLStore statement of key, prepared once per connection.
+// A statement invalidated by a schema change is prepared again and retried once.
+func (c *Client) ExecByKey(ctx context.Context, key string, args ...any) (sqldb.Result, error) {
+	sql, ok := c.RawSQLStore().Get(key)
+	if !ok {
+		return nil, &sqldb.ErrRawSQLKeyNotFound{Key: key}
+	}
+	conn, err := c.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+	var tag pgconn.CommandTag
+	for attempt := 0; attempt < 2; attempt++ {
+		var name string
+		if name, err = c.preparedName(ctx, conn, key, sql); err != nil {
+			return nil, err
+		}
+		tag, err = conn.Exec(ctx, name, args...)
+		if err == nil || !isStmtInvalidErr(err) {
+			break
+		}
+		c.invalidate(conn, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Result{tag: tag}, nil
+}
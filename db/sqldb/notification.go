@@ -4,4 +4,7 @@ type Notification struct {
 	PID     uint32 // process ID of the backend that sent the notification
 	Channel string // channel name
 	Payload string // message payload
+	// Gap is set on a synthetic notification (no Channel, no Payload)
+	// sent when notifications may have been lost. e.g. after the subscription was re-established
+	Gap bool
 }
@@ -0,0 +1,19 @@
+package sqldb
+
+// uniqueChildIDsAsAny collects the distinct child ids of pivot rows, preserving first occurrence order
+func uniqueChildIDsAsAny[V any, PID comparable, CID comparable](
+	pivotRows []V,
+	pivotKeys func(V) (PID, CID),
+) []any {
+	ids := make([]any, 0, len(pivotRows))
+	seen := make(map[CID]struct{}, len(pivotRows))
+	for _, pivotRow := range pivotRows {
+		_, cid := pivotKeys(pivotRow)
+		if _, exists := seen[cid]; exists {
+			continue
+		}
+		seen[cid] = struct{}{}
+		ids = append(ids, cid)
+	}
+	return ids
+}
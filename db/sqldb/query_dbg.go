@@ -61,9 +61,11 @@ func LoadHasMany[
 	foreignKeyColumn Column, // on the child
 	foreignKey func(CP) PID, // on the child
 	relationFieldPtr func(PP) **coll.Collection[CP, CID], // on the parent
+	orderBys ...OrderBy,
 ) (*coll.Collection[CP, CID], error) {
-	sqlStmt := sqlSelectBase + fmt.Sprintf(" WHERE %s IN (%s)", foreignKeyColumn.Name(),
-		dbClient.Placeholders(parents.Len(), 2))
+	whereClause := fmt.Sprintf(" WHERE %s IN (%s)", foreignKeyColumn.Name(), dbClient.Placeholders(parents.Len()))
+	sqlStmt := sqlSelectBase + whereClause + OrderByClause(orderBys)
+	log.Printf("[DEBUG] LoadHasMany() sqlStmt %s", sqlStmt)
 	parentIDsAsAny := parents.IDsAsAny()
 	children, err := RawQueryCollection[C, CP, CID](ctx, dbClient, sqlStmt, parentIDsAsAny...)
 	if err != nil {
@@ -77,3 +79,60 @@ func LoadHasMany[
 	)
 	return children, nil
 }
+
+func LoadManyToMany[
+	PP model.Identifiable[PID],
+	PID comparable,
+	V any, // Pivot Model struct
+	VP Scannable[V],
+	C any, // Model struct
+	CP ScannableIdentifiable[C, CID],
+	CID comparable,
+](
+	ctx context.Context,
+	dbClient Client,
+	parents *coll.Collection[PP, PID],
+	pivotSelectBase string,
+	pivotParentKeyColumn Column, // on the pivot
+	pivotKeys func(VP) (PID, CID), // on the pivot
+	sqlSelectBase string,
+	relationFieldPtr func(PP) **coll.Collection[CP, CID], // on the parent
+	pivotFieldPtr func(PP) *map[CID]VP, // on the parent. optional
+	orderBys ...OrderBy, // for the children
+) (*coll.Collection[CP, CID], []VP, error) {
+	var pivotRows []VP
+	children := coll.NewEmptyOrderedCollection[CP, CID]()
+	if parents.Len() > 0 {
+		pivotStmt := pivotSelectBase + fmt.Sprintf(" WHERE %s IN (%s)", pivotParentKeyColumn.Name(), dbClient.Placeholders(parents.Len()))
+		log.Printf("[DEBUG] LoadManyToMany() pivotStmt %s", pivotStmt)
+		pivots, err := RawQueryItems[V, VP](ctx, dbClient, pivotStmt, parents.IDsAsAny()...)
+		if err != nil {
+			return nil, nil, err
+		}
+		pivotRows = make([]VP, len(pivots))
+		for i, p := range pivots {
+			pivotRows[i] = p
+		}
+	}
+	log.Printf("[DEBUG] LoadManyToMany() %d pivot rows", len(pivotRows))
+	if len(pivotRows) > 0 {
+		childIDsAsAny := uniqueChildIDsAsAny(pivotRows, pivotKeys)
+		whereClause := fmt.Sprintf(" WHERE id IN (%s)", dbClient.Placeholders(len(childIDsAsAny)))
+		sqlStmt := sqlSelectBase + whereClause + OrderByClause(orderBys)
+		log.Printf("[DEBUG] LoadManyToMany() sqlStmt %s", sqlStmt)
+		var err error
+		children, err = RawQueryCollection[C, CP, CID](ctx, dbClient, sqlStmt, childIDsAsAny...)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	coll.LinkManyToMany[PP, PID, CP, CID, VP](
+		parents,
+		children,
+		pivotRows,
+		pivotKeys,
+		relationFieldPtr,
+		pivotFieldPtr,
+	)
+	return children, pivotRows, nil
+}
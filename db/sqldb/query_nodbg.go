@@ -73,3 +73,62 @@ func LoadHasMany[
 	)
 	return children, nil
 }
+
+// LoadManyToMany - Load Children of Parents joined through a Pivot table and Link Parent-ManyToMany-Children Relation
+// 1st query: pivot rows of the parents (pivotSelectBase + WHERE pivotParentKeyColumn IN parent ids)
+// 2nd query: children in the pivot rows (sqlSelectBase + WHERE id IN child ids)
+// Extra pivot columns are exposed by scanning them into the pivot model and giving pivotFieldPtr (optional)
+// Returns the Children and the Pivot rows
+func LoadManyToMany[
+	PP model.Identifiable[PID],
+	PID comparable,
+	V any, // Pivot Model struct
+	VP Scannable[V],
+	C any, // Model struct
+	CP ScannableIdentifiable[C, CID],
+	CID comparable,
+](
+	ctx context.Context,
+	dbClient Client,
+	parents *coll.Collection[PP, PID],
+	pivotSelectBase string,
+	pivotParentKeyColumn Column, // on the pivot
+	pivotKeys func(VP) (PID, CID), // on the pivot
+	sqlSelectBase string,
+	relationFieldPtr func(PP) **coll.Collection[CP, CID], // on the parent
+	pivotFieldPtr func(PP) *map[CID]VP, // on the parent. optional
+	orderBys ...OrderBy, // for the children
+) (*coll.Collection[CP, CID], []VP, error) {
+	var pivotRows []VP
+	children := coll.NewEmptyOrderedCollection[CP, CID]()
+	if parents.Len() > 0 {
+		pivotStmt := pivotSelectBase + fmt.Sprintf(" WHERE %s IN (%s)", pivotParentKeyColumn.Name(), dbClient.Placeholders(parents.Len()))
+		pivots, err := RawQueryItems[V, VP](ctx, dbClient, pivotStmt, parents.IDsAsAny()...)
+		if err != nil {
+			return nil, nil, err
+		}
+		pivotRows = make([]VP, len(pivots))
+		for i, p := range pivots {
+			pivotRows[i] = p
+		}
+	}
+	if len(pivotRows) > 0 {
+		childIDsAsAny := uniqueChildIDsAsAny(pivotRows, pivotKeys)
+		whereClause := fmt.Sprintf(" WHERE id IN (%s)", dbClient.Placeholders(len(childIDsAsAny)))
+		sqlStmt := sqlSelectBase + whereClause + OrderByClause(orderBys)
+		var err error
+		children, err = RawQueryCollection[C, CP, CID](ctx, dbClient, sqlStmt, childIDsAsAny...)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	coll.LinkManyToMany[PP, PID, CP, CID, VP](
+		parents,
+		children,
+		pivotRows,
+		pivotKeys,
+		relationFieldPtr,
+		pivotFieldPtr,
+	)
+	return children, pivotRows, nil
+}
@@ -0,0 +1,243 @@
+package sqldb
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// RawLoadReport describes a RawSQLStore load
+type RawLoadReport struct {
+	DBType   string
+	Loaded   int               // statements in the store
+	Shadowed []string          // keys present in both .sql and .<dbType>. The dialect file wins.
+	Invalid  map[string]string // key -> validation error
+	Skipped  []string          // [VerifyRawSQLStore] keys not verifiable against the live DB (dynamic placeholders, multiple statements)
+}
+
+// OK reports whether no statement is invalid
+func (r *RawLoadReport) OK() bool {
+	return len(r.Invalid) == 0
+}
+
+// Err returns an error listing the invalid statements, or nil
+func (r *RawLoadReport) Err() error {
+	if r.OK() {
+		return nil
+	}
+	keys := make([]string, 0, len(r.Invalid))
+	for k := range r.Invalid {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d invalid raw SQL statement(s)", len(keys))
+	for _, k := range keys {
+		fmt.Fprintf(&b, "\n  %s: %s", k, r.Invalid[k])
+	}
+	return fmt.Errorf("%s", b.String())
+}
+
+// LoadRawSQLStore builds a new RawSQLStore for dbType from the *.sql and *.<dbType> files in sqlFS.
+// Keys are the file paths without the extension, with '/' replaced by '.' ("foo/bar/find.sql" -> "foo.bar.find").
+// A *.<dbType> file is used as-is. A *.sql file is used only if there is no dialect file for the key,
+// with its static placeholders `?` converted for dbType.
+// Every statement is validated with ValidateRawSQL. The store is returned with the report even if some are invalid,
+// so check report.Err() before using it.
+func LoadRawSQLStore(sqlFS fs.FS, dbType string) (*RawSQLStore, *RawLoadReport, error) {
+	prefix, ok := PlaceholderPrefixForDBType[dbType]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown db type: %s", dbType)
+	}
+	dialectStmts := make(map[string]string)
+	stdStmts := make(map[string]string)
+	err := fs.WalkDir(sqlFS, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// error reading a directory
+			return err
+		}
+		if d.IsDir() {
+			// Skip directory itself. still walking into it.
+			return nil
+		}
+		ext := filepath.Ext(path) // with the leading dot
+		if ext == "" {
+			return nil
+		}
+		plainExt := strings.TrimPrefix(ext, ".")
+		if plainExt != dbType && plainExt != "sql" {
+			return nil
+		}
+		data, err := fs.ReadFile(sqlFS, path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		// fs.FS always stores paths using forward slashes '/', regardless of the OS.
+		key := strings.TrimSuffix(path, ext)
+		key = strings.TrimPrefix(key, "./") // just in case that fs is not an embed.fs
+		key = strings.ReplaceAll(key, "/", ".")
+		if plainExt == dbType {
+			dialectStmts[key] = string(data)
+		} else {
+			stdStmts[key] = string(data)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	store := NewRawStore()
+	report := &RawLoadReport{DBType: dbType, Invalid: make(map[string]string)}
+	for key, sql := range stdStmts {
+		if _, exists := dialectStmts[key]; exists {
+			report.Shadowed = append(report.Shadowed, key)
+			continue
+		}
+		if err = ValidateRawSQL(sql, '?'); err != nil {
+			report.Invalid[key] = err.Error()
+		}
+		store.Set(key, ReplaceStaticPlaceholders(sql, prefix))
+	}
+	for key, sql := range dialectStmts {
+		if err = ValidateRawSQL(sql, prefix); err != nil {
+			report.Invalid[key] = err.Error()
+		}
+		store.Set(key, sql)
+	}
+	slices.Sort(report.Shadowed)
+	report.Loaded = len(store.stmts)
+	return store, report, nil
+}
+
+// ValidateRawSQL checks a raw statement written with placeholders of prefix ('?' for standard *.sql files).
+// Quotes, comments and parentheses must be balanced, dynamic placeholders must be exactly `??`,
+// and ordinal placeholders ($1, $2, ...) must be numbered from 1 without gaps.
+// Quoted strings, quoted identifiers and comments are ignored.
+// Backslash escapes in quoted strings are honored for '?' (MySQL, and the standard files that must also run there),
+// and in PostgreSQL E'...' strings.
+func ValidateRawSQL(sql string, prefix byte) error {
+	depth := 0
+	ordinals := make(map[int]struct{})
+	maxOrdinal := 0
+	for i := 0; i < len(sql); i++ {
+		ch := sql[i]
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			backslashEscapes := ch != '`' && (prefix == '?' || ch == '\'' && isEscapeStringPrefix(sql, i))
+			end := quoteEnd(sql, i, backslashEscapes)
+			if end == -1 {
+				return fmt.Errorf("unterminated quote %c at offset %d", ch, i)
+			}
+			i = end
+		case ch == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			end := strings.IndexByte(sql[i:], '\n')
+			if end == -1 {
+				end = len(sql) - i
+			}
+			i += end
+		case ch == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			end := strings.Index(sql[i+2:], "*/")
+			if end == -1 {
+				return fmt.Errorf("unterminated comment at offset %d", i)
+			}
+			i += end + 3
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("unbalanced ')' at offset %d", i)
+			}
+		case ch == '?':
+			run := 1
+			for i+run < len(sql) && sql[i+run] == '?' {
+				run++
+			}
+			if run > 2 {
+				return fmt.Errorf("invalid placeholder %q at offset %d", sql[i:i+run], i)
+			}
+			i += run - 1
+		case ch == prefix && prefix != '?' && prefix != 0:
+			j := i + 1
+			for j < len(sql) && sql[j] >= '0' && sql[j] <= '9' {
+				j++
+			}
+			if j == i+1 {
+				continue // not a placeholder. e.g. $$ quoting
+			}
+			n, _ := strconv.Atoi(sql[i+1 : j])
+			if n == 0 {
+				return fmt.Errorf("invalid placeholder %q at offset %d", sql[i:j], i)
+			}
+			ordinals[n] = struct{}{}
+			maxOrdinal = max(maxOrdinal, n)
+			i = j - 1
+		}
+	}
+	if depth != 0 {
+		return fmt.Errorf("%d unclosed '('", depth)
+	}
+	for n := 1; n <= maxOrdinal; n++ {
+		if _, ok := ordinals[n]; !ok {
+			return fmt.Errorf("placeholder %c%d missing (max %c%d)", prefix, n, prefix, maxOrdinal)
+		}
+	}
+	return nil
+}
+
+// quoteEnd returns the offset of the quote closing the one at start. -1 if unterminated
+func quoteEnd(sql string, start int, backslashEscapes bool) int {
+	quote := sql[start]
+	for i := start + 1; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			if backslashEscapes {
+				i++ // skip the escaped char
+			}
+		case quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// isEscapeStringPrefix reports whether the quote at i starts a PostgreSQL escape string: E'...'
+func isEscapeStringPrefix(sql string, i int) bool {
+	if i == 0 || (sql[i-1] != 'E' && sql[i-1] != 'e') {
+		return false
+	}
+	if i == 1 {
+		return true
+	}
+	c := sql[i-2]
+	return !(c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z')
+}
+
+// VerifyRawSQLStore prepares every statement of the store against the live DB without executing it.
+// Statements with dynamic placeholders or multiple statements cannot be prepared, so they are reported as skipped.
+// Failures are recorded in report.Invalid.
+func VerifyRawSQLStore(ctx context.Context, dbClient Client, store *RawSQLStore, report *RawLoadReport) {
+	keys := make([]string, 0, len(store.stmts))
+	for k := range store.stmts {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		sql := store.stmts[key]
+		if strings.Contains(sql, "??") || strings.Contains(strings.TrimRight(strings.TrimSpace(sql), ";"), ";") {
+			report.Skipped = append(report.Skipped, key)
+			continue
+		}
+		stmt, err := dbClient.Prepare(ctx, sql)
+		if err != nil {
+			report.Invalid[key] = err.Error()
+			continue
+		}
+		_ = stmt.Close()
+	}
+}
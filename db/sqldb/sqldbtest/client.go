@@ -0,0 +1,319 @@
+// Package sqldbtest provides a scriptable fake sqldb.Client to test code using sqldb without a database.
+//
+//	db := sqldbtest.NewClient("pgsql")
+//	db.ExpectQuery(`FROM users WHERE id = \$1`).WithArgs(7).
+//		WillReturnRows(sqldbtest.RowsFromItems[User, *User](&User{ID: 7, Name: "kim"}))
+//	user, err := sqldb.RawQueryItem[User, *User](ctx, db, "SELECT id, name FROM users WHERE id = $1", 7)
+//	...
+//	if err := db.ExpectationsWereMet(); err != nil {
+//		t.Error(err)
+//	}
+//
+// Expectations are matched in the order they were added.
+package sqldbtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/logitools/gw/db/sqldb"
+)
+
+type Client struct {
+	conf     *sqldb.Conf
+	prefix   byte
+	mu       sync.Mutex
+	rawStore *sqldb.RawSQLStore
+	expects  []*Expectation
+	failures []string // unexpected calls
+	// Listen/Notify
+	listeners []chan sqldb.Notification
+	listenCh  [][]string
+}
+
+// Ensure sqldbtest.Client implements sqldb.Client interface
+var _ sqldb.Client = (*Client)(nil)
+
+// NewClient creates a fake client of dbType ("pgsql", "mysql", ...), which decides the placeholders
+func NewClient(dbType string) *Client {
+	return &Client{
+		conf:     &sqldb.Conf{Type: dbType},
+		prefix:   sqldb.PlaceholderPrefixForDBType[dbType],
+		rawStore: sqldb.NewRawStore(),
+	}
+}
+
+//---- Expectations ----
+
+func (c *Client) expect(e *Expectation) *Expectation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expects = append(c.expects, e)
+	return e
+}
+
+// ExpectQuery expects a query whose SQL matches the regular expression
+func (c *Client) ExpectQuery(sqlRegex string) *Expectation {
+	return c.expect(&Expectation{kind: kindQuery, sqlRegex: regexp.MustCompile(sqlRegex)})
+}
+
+// ExpectQueryKey expects a query by QueryByKey(key), or with the SQL of key in the RawSQLStore
+func (c *Client) ExpectQueryKey(key string) *Expectation {
+	return c.expect(&Expectation{kind: kindQuery, key: key})
+}
+
+// ExpectExec expects an Exec/InsertStmt whose SQL matches the regular expression
+func (c *Client) ExpectExec(sqlRegex string) *Expectation {
+	return c.expect(&Expectation{kind: kindExec, sqlRegex: regexp.MustCompile(sqlRegex)})
+}
+
+// ExpectExecKey expects an exec by ExecByKey(key), or with the SQL of key in the RawSQLStore
+func (c *Client) ExpectExecKey(key string) *Expectation {
+	return c.expect(&Expectation{kind: kindExec, key: key})
+}
+
+// ExpectCopyFrom expects a CopyFrom into table. It returns the number of rows unless WillReturnResult is set.
+func (c *Client) ExpectCopyFrom(table string) *Expectation {
+	return c.expect(&Expectation{kind: kindCopyFrom, table: table})
+}
+
+func (c *Client) ExpectBegin() *Expectation {
+	return c.expect(&Expectation{kind: kindBegin})
+}
+
+func (c *Client) ExpectCommit() *Expectation {
+	return c.expect(&Expectation{kind: kindCommit})
+}
+
+func (c *Client) ExpectRollback() *Expectation {
+	return c.expect(&Expectation{kind: kindRollback})
+}
+
+// ExpectationsWereMet returns an error listing the unexpected calls and the expectations not met
+func (c *Client) ExpectationsWereMet() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	problems := append([]string(nil), c.failures...)
+	for _, e := range c.expects {
+		if !e.met {
+			problems = append(problems, "not met: "+e.String())
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New("sqldbtest: " + strings.Join(problems, "\n  "))
+}
+
+// dispatch matches the call against the next expectation not met yet
+func (c *Client) dispatch(cl *call) (*Expectation, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.expects {
+		if e.met {
+			continue
+		}
+		if err := e.match(cl, c.rawStore); err != nil {
+			msg := fmt.Sprintf("unexpected %s: %v", cl, err)
+			c.failures = append(c.failures, msg)
+			return nil, errors.New("sqldbtest: " + msg)
+		}
+		e.met = true
+		if e.err != nil {
+			return nil, e.err
+		}
+		return e, nil
+	}
+	msg := fmt.Sprintf("unexpected %s: no more expectations", cl)
+	c.failures = append(c.failures, msg)
+	return nil, errors.New("sqldbtest: " + msg)
+}
+
+func (c *Client) query(cl *call) (sqldb.Rows, error) {
+	e, err := c.dispatch(cl)
+	if err != nil {
+		return nil, err
+	}
+	if e.rows == nil {
+		return NewRows(), nil
+	}
+	return e.rows.clone(), nil
+}
+
+func (c *Client) exec(cl *call) (sqldb.Result, error) {
+	e, err := c.dispatch(cl)
+	if err != nil {
+		return nil, err
+	}
+	if e.result == nil {
+		return &Result{}, nil
+	}
+	return e.result, nil
+}
+
+//---- sqldb.Client ----
+
+func (c *Client) DBHandle() sqldb.Handle {
+	return c
+}
+
+func (c *Client) Conf() *sqldb.Conf {
+	return c.conf
+}
+
+func (c *Client) DSN() string {
+	return "sqldbtest"
+}
+
+func (c *Client) SinglePlaceholder(nth ...int) string {
+	return sqldb.PlaceholderGF(c.prefix)(nth...)
+}
+
+func (c *Client) Placeholders(cnt int, start ...int) string {
+	return strings.Join(sqldb.PlaceholdersGF(c.prefix)(cnt, start...), ",")
+}
+
+func (c *Client) RawSQLStore() *sqldb.RawSQLStore {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rawStore
+}
+
+func (c *Client) SetRawSQLStore(store *sqldb.RawSQLStore) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rawStore = store
+}
+
+func (c *Client) QueryByKey(_ context.Context, key string, args ...any) (sqldb.Rows, error) {
+	query, ok := c.RawSQLStore().Get(key)
+	if !ok {
+		return nil, &sqldb.ErrRawSQLKeyNotFound{Key: key}
+	}
+	return c.query(&call{kind: kindQuery, query: query, key: key, args: args})
+}
+
+func (c *Client) ExecByKey(_ context.Context, key string, args ...any) (sqldb.Result, error) {
+	query, ok := c.RawSQLStore().Get(key)
+	if !ok {
+		return nil, &sqldb.ErrRawSQLKeyNotFound{Key: key}
+	}
+	return c.exec(&call{kind: kindExec, query: query, key: key, args: args})
+}
+
+func (c *Client) Init() error {
+	return nil
+}
+
+func (c *Client) Open(_ context.Context) error {
+	return nil
+}
+
+func (c *Client) Ping(_ context.Context) error {
+	return nil
+}
+
+func (c *Client) Close() error {
+	return nil
+}
+
+func (c *Client) BeginTx(_ context.Context) (sqldb.Tx, error) {
+	if _, err := c.dispatch(&call{kind: kindBegin}); err != nil {
+		return nil, err
+	}
+	return &Tx{client: c}, nil
+}
+
+//---- sqldb.Handle ----
+
+func (c *Client) Exec(_ context.Context, query string, args ...any) (sqldb.Result, error) {
+	return c.exec(&call{kind: kindExec, query: query, args: args})
+}
+
+func (c *Client) QueryRows(_ context.Context, query string, args ...any) (sqldb.Rows, error) {
+	return c.query(&call{kind: kindQuery, query: query, args: args})
+}
+
+func (c *Client) QueryRow(_ context.Context, query string, args ...any) sqldb.Row {
+	rows, err := c.query(&call{kind: kindQuery, query: query, args: args})
+	if err != nil {
+		return &errRow{err: err}
+	}
+	return rows.(*Rows)
+}
+
+func (c *Client) CopyFrom(_ context.Context, table string, _ []string, rows [][]any) (int64, error) {
+	e, err := c.dispatch(&call{kind: kindCopyFrom, table: table})
+	if err != nil {
+		return 0, err
+	}
+	if e.result != nil {
+		return e.result.rowsAffected, nil
+	}
+	return int64(len(rows)), nil
+}
+
+// Listen receives what Notify sends on this fake client until ctx is done
+func (c *Client) Listen(ctx context.Context, channels ...string) (<-chan sqldb.Notification, error) {
+	if len(channels) == 0 {
+		return nil, errors.New("no channels to listen")
+	}
+	ch := make(chan sqldb.Notification, 64)
+	c.mu.Lock()
+	c.listeners = append(c.listeners, ch)
+	c.listenCh = append(c.listenCh, channels)
+	c.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for i, l := range c.listeners {
+			if l == ch {
+				c.listeners = append(c.listeners[:i], c.listeners[i+1:]...)
+				c.listenCh = append(c.listenCh[:i], c.listenCh[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Notify delivers to the listeners of this fake client. It needs no expectation.
+func (c *Client) Notify(_ context.Context, channel string, payload string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, l := range c.listeners {
+		for _, listened := range c.listenCh[i] {
+			if listened == channel {
+				select {
+				case l <- sqldb.Notification{Channel: channel, Payload: payload}:
+				default: // listener not keeping up
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Client) Prepare(_ context.Context, query string) (sqldb.PreparedStmt, error) {
+	return &PreparedStmt{client: c, query: query}, nil
+}
+
+func (c *Client) InsertStmt(_ context.Context, query string, args ...any) (sqldb.Result, error) {
+	return c.exec(&call{kind: kindExec, query: query, args: args})
+}
+
+// errRow is a sqldb.Row failing with err
+type errRow struct {
+	err error
+}
+
+func (r *errRow) Scan(_ ...any) error {
+	return r.err
+}
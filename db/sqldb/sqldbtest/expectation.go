@@ -0,0 +1,145 @@
+package sqldbtest
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/logitools/gw/db/sqldb"
+)
+
+// kinds of calls
+const (
+	kindQuery    = "query"
+	kindExec     = "exec"
+	kindBegin    = "begin"
+	kindCommit   = "commit"
+	kindRollback = "rollback"
+	kindCopyFrom = "copyfrom"
+)
+
+// anyArg matches any argument value
+type anyArg struct{}
+
+// AnyArg matches any value in WithArgs
+var AnyArg = anyArg{}
+
+// Expectation is one expected call. Configure it with the chainable With*/Will* methods.
+type Expectation struct {
+	kind     string
+	sqlRegex *regexp.Regexp // nil for key expectations and Tx calls
+	key      string         // RawSQLStore key
+	table    string         // CopyFrom
+	args     []any          // nil: any args
+	rows     *Rows
+	result   *Result
+	err      error
+	met      bool
+}
+
+// WithArgs requires the call args to deep-equal args. Use AnyArg to skip a position.
+func (e *Expectation) WithArgs(args ...any) *Expectation {
+	if args == nil {
+		args = []any{}
+	}
+	e.args = args
+	return e
+}
+
+// WillReturnRows sets the rows returned by a query
+func (e *Expectation) WillReturnRows(rows *Rows) *Expectation {
+	e.rows = rows
+	return e
+}
+
+// WillReturnResult sets the result of an exec
+func (e *Expectation) WillReturnResult(rowsAffected int64, lastInsertID int64) *Expectation {
+	e.result = &Result{rowsAffected: rowsAffected, lastInsertID: lastInsertID}
+	return e
+}
+
+// WillReturnError makes the call fail with err
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+func (e *Expectation) String() string {
+	switch {
+	case e.key != "":
+		return fmt.Sprintf("%s by key %q", e.kind, e.key)
+	case e.sqlRegex != nil:
+		return fmt.Sprintf("%s matching %q", e.kind, e.sqlRegex.String())
+	case e.table != "":
+		return fmt.Sprintf("%s into %q", e.kind, e.table)
+	default:
+		return e.kind
+	}
+}
+
+// call is an actual call to match against the expectations
+type call struct {
+	kind  string
+	query string // SQL text
+	key   string // set by QueryByKey/ExecByKey
+	table string
+	args  []any
+}
+
+func (c *call) String() string {
+	var b strings.Builder
+	b.WriteString(c.kind)
+	if c.key != "" {
+		fmt.Fprintf(&b, " by key %q", c.key)
+	}
+	if c.query != "" {
+		fmt.Fprintf(&b, " %q", c.query)
+	}
+	if c.table != "" {
+		fmt.Fprintf(&b, " into %q", c.table)
+	}
+	if len(c.args) > 0 {
+		fmt.Fprintf(&b, " args %v", c.args)
+	}
+	return b.String()
+}
+
+// match reports whether e matches the call. store resolves key expectations to SQL text.
+func (e *Expectation) match(c *call, store *sqldb.RawSQLStore) error {
+	if e.kind != c.kind {
+		return fmt.Errorf("expected %s", e)
+	}
+	switch {
+	case e.key != "":
+		if c.key != e.key {
+			keySQL, ok := store.Get(e.key)
+			if !ok || keySQL != c.query {
+				return fmt.Errorf("expected %s", e)
+			}
+		}
+	case e.sqlRegex != nil:
+		if !e.sqlRegex.MatchString(c.query) {
+			return fmt.Errorf("expected %s", e)
+		}
+	case e.table != "":
+		if e.table != c.table {
+			return fmt.Errorf("expected %s", e)
+		}
+	}
+	if e.args == nil {
+		return nil
+	}
+	if len(e.args) != len(c.args) {
+		return fmt.Errorf("%s: expected %d args, got %d", e, len(e.args), len(c.args))
+	}
+	for i, want := range e.args {
+		if want == AnyArg {
+			continue
+		}
+		if !reflect.DeepEqual(want, c.args[i]) {
+			return fmt.Errorf("%s: arg %d: expected %#v, got %#v", e, i, want, c.args[i])
+		}
+	}
+	return nil
+}
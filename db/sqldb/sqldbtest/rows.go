@@ -0,0 +1,156 @@
+package sqldbtest
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+
+	"github.com/logitools/gw/db/sqldb"
+)
+
+// Rows is a fake sqldb.Rows over Go values. It is also a sqldb.Row over its first row.
+type Rows struct {
+	values  [][]any
+	pos     int   // 1-based index of the current row. 0 = before the first row
+	nextErr error // returned by Err() after all rows
+	closed  bool
+}
+
+// Ensure sqldbtest.Rows implements sqldb.Rows and sqldb.Row
+var _ sqldb.Rows = (*Rows)(nil)
+var _ sqldb.Row = (*Rows)(nil)
+
+// NewRows creates rows from the column values of each row
+func NewRows(rows ...[]any) *Rows {
+	return &Rows{values: rows}
+}
+
+// RowsFromItems creates rows from model items. Each row holds the values pointed by FieldsToScan,
+// so scanning them with ScanRowsToItems, RawQueryItems, etc. reproduces the items.
+func RowsFromItems[
+	M any, // Model struct
+	MP sqldb.Scannable[M], // *Model Implementing Scannable[M]
+](items ...*M) *Rows {
+	rows := make([][]any, len(items))
+	for i, item := range items {
+		fields := MP(item).FieldsToScan()
+		values := make([]any, len(fields))
+		for j, f := range fields {
+			values[j] = reflect.ValueOf(f).Elem().Interface()
+		}
+		rows[i] = values
+	}
+	return NewRows(rows...)
+}
+
+// AddRow appends a row
+func (r *Rows) AddRow(values ...any) *Rows {
+	r.values = append(r.values, values)
+	return r
+}
+
+// WithIterationError makes Err() return err once the rows are exhausted
+func (r *Rows) WithIterationError(err error) *Rows {
+	r.nextErr = err
+	return r
+}
+
+// clone gives each returned Rows its own cursor
+func (r *Rows) clone() *Rows {
+	return &Rows{values: r.values, nextErr: r.nextErr}
+}
+
+func (r *Rows) Next() bool {
+	if r.closed || r.pos >= len(r.values) {
+		return false
+	}
+	r.pos++
+	return true
+}
+
+// Scan assigns the current row (the first row if Next was not called) to dest
+func (r *Rows) Scan(dest ...any) error {
+	if r.pos == 0 {
+		// Used as sqldb.Row
+		if len(r.values) == 0 {
+			return sqldb.ErrNoRows
+		}
+		return scanValues(r.values[0], dest)
+	}
+	return scanValues(r.values[r.pos-1], dest)
+}
+
+func (r *Rows) Close() error {
+	r.closed = true
+	return nil
+}
+
+func (r *Rows) Err() error {
+	if r.pos >= len(r.values) {
+		return r.nextErr
+	}
+	return nil
+}
+
+func (r *Rows) NextResultSet() bool {
+	return false
+}
+
+// scanValues assigns values to dest pointers, converting between convertible types (e.g. int64 -> int)
+func scanValues(values []any, dest []any) error {
+	if len(values) != len(dest) {
+		return fmt.Errorf("sqldbtest: row has %d columns, scanning into %d", len(values), len(dest))
+	}
+	for i, v := range values {
+		if scanner, ok := dest[i].(sql.Scanner); ok {
+			if err := scanner.Scan(v); err != nil {
+				return fmt.Errorf("sqldbtest: column %d: %w", i, err)
+			}
+			continue
+		}
+		dv := reflect.ValueOf(dest[i])
+		if dv.Kind() != reflect.Pointer || dv.IsNil() {
+			return fmt.Errorf("sqldbtest: column %d: destination not a non-nil pointer", i)
+		}
+		target := dv.Elem()
+		if v == nil {
+			target.SetZero()
+			continue
+		}
+		vv := reflect.ValueOf(v)
+		switch {
+		case vv.Type().AssignableTo(target.Type()):
+			target.Set(vv)
+		case vv.Type().ConvertibleTo(target.Type()) && vv.Kind() != reflect.String && target.Kind() != reflect.String:
+			target.Set(vv.Convert(target.Type()))
+		case target.Kind() == reflect.Pointer && vv.Type().AssignableTo(target.Type().Elem()):
+			// e.g. string into *string (nullable column)
+			p := reflect.New(target.Type().Elem())
+			p.Elem().Set(vv)
+			target.Set(p)
+		default:
+			return fmt.Errorf("sqldbtest: column %d: cannot assign %T to %s", i, v, target.Type())
+		}
+	}
+	return nil
+}
+
+// Result is a fake sqldb.Result
+type Result struct {
+	rowsAffected int64
+	lastInsertID int64
+}
+
+var _ sqldb.Result = (*Result)(nil)
+
+func NewResult(rowsAffected int64, lastInsertID int64) *Result {
+	return &Result{rowsAffected: rowsAffected, lastInsertID: lastInsertID}
+}
+
+func (r *Result) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+func (r *Result) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
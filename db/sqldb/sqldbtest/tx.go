@@ -0,0 +1,70 @@
+package sqldbtest
+
+import (
+	"context"
+	"errors"
+
+	"github.com/logitools/gw/db/sqldb"
+)
+
+// Tx is a fake sqldb.Tx. Its calls are matched against the expectations of the client.
+type Tx struct {
+	client *Client
+	done   bool
+}
+
+var _ sqldb.Tx = (*Tx)(nil)
+
+var errTxDone = errors.New("sqldbtest: transaction already committed or rolled back")
+
+func (t *Tx) Commit(_ context.Context) error {
+	if t.done {
+		return errTxDone
+	}
+	t.done = true
+	_, err := t.client.dispatch(&call{kind: kindCommit})
+	return err
+}
+
+func (t *Tx) Rollback(_ context.Context) error {
+	if t.done {
+		return errTxDone
+	}
+	t.done = true
+	_, err := t.client.dispatch(&call{kind: kindRollback})
+	return err
+}
+
+func (t *Tx) Exec(_ context.Context, query string, args ...any) (sqldb.Result, error) {
+	if t.done {
+		return nil, errTxDone
+	}
+	return t.client.exec(&call{kind: kindExec, query: query, args: args})
+}
+
+func (t *Tx) Query(_ context.Context, query string, args ...any) (sqldb.Rows, error) {
+	if t.done {
+		return nil, errTxDone
+	}
+	return t.client.query(&call{kind: kindQuery, query: query, args: args})
+}
+
+// PreparedStmt is a fake sqldb.PreparedStmt. Its calls are matched with the prepared query.
+type PreparedStmt struct {
+	client *Client
+	query  string
+}
+
+var _ sqldb.PreparedStmt = (*PreparedStmt)(nil)
+
+func (p *PreparedStmt) Query(_ context.Context, args ...any) (sqldb.Rows, error) {
+	return p.client.query(&call{kind: kindQuery, query: p.query, args: args})
+}
+
+func (p *PreparedStmt) Exec(_ context.Context, args ...any) (sqldb.Result, error) {
+	return p.client.exec(&call{kind: kindExec, query: p.query, args: args})
+}
+
+func (p *PreparedStmt) Close() error {
+	return nil
+}
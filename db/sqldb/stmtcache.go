@@ -0,0 +1,107 @@
+package sqldb
+
+import (
+	"container/list"
+	"fmt"
+)
+
+// DefaultStmtCacheSize is the default max number of cached prepared statements (per connection for pgsql)
+const DefaultStmtCacheSize = 256
+
+// ErrRawSQLKeyNotFound is returned by QueryByKey/ExecByKey for a key not in the RawSQLStore
+type ErrRawSQLKeyNotFound struct {
+	Key string
+}
+
+func (e *ErrRawSQLKeyNotFound) Error() string {
+	return fmt.Sprintf("raw SQL not found for key: %s", e.Key)
+}
+
+// StmtCache is an LRU cache of prepared statements S keyed by RawSQLStore key.
+// An entry only hits when its SQL is still the same, so a reloaded statement is prepared again.
+// Not goroutine-safe. Guard it with a lock, or confine it to one connection.
+type StmtCache[S any] struct {
+	capacity int
+	ll       *list.List               // front = most recently used
+	items    map[string]*list.Element // key -> *stmtCacheEntry[S]
+}
+
+type stmtCacheEntry[S any] struct {
+	key  string
+	sql  string
+	stmt S
+}
+
+// NewStmtCache creates a cache holding up to capacity statements (DefaultStmtCacheSize if <= 0)
+func NewStmtCache[S any](capacity int) *StmtCache[S] {
+	if capacity <= 0 {
+		capacity = DefaultStmtCacheSize
+	}
+	return &StmtCache[S]{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the statement prepared for key with the same sql
+func (c *StmtCache[S]) Get(key string, sql string) (S, bool) {
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*stmtCacheEntry[S])
+		if entry.sql == sql {
+			c.ll.MoveToFront(el)
+			return entry.stmt, true
+		}
+	}
+	var zero S
+	return zero, false
+}
+
+// Put caches stmt for key.
+// Returns the statements dropped from the cache (replaced or evicted), which the caller must close.
+func (c *StmtCache[S]) Put(key string, sql string, stmt S) []S {
+	var dropped []S
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*stmtCacheEntry[S])
+		dropped = append(dropped, entry.stmt)
+		entry.sql = sql
+		entry.stmt = stmt
+		c.ll.MoveToFront(el)
+		return dropped
+	}
+	c.items[key] = c.ll.PushFront(&stmtCacheEntry[S]{key: key, sql: sql, stmt: stmt})
+	for c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		entry := c.ll.Remove(oldest).(*stmtCacheEntry[S])
+		delete(c.items, entry.key)
+		dropped = append(dropped, entry.stmt)
+	}
+	return dropped
+}
+
+// Remove drops the statement for key. Returns it to be closed by the caller.
+func (c *StmtCache[S]) Remove(key string) (S, bool) {
+	el, ok := c.items[key]
+	if !ok {
+		var zero S
+		return zero, false
+	}
+	entry := c.ll.Remove(el).(*stmtCacheEntry[S])
+	delete(c.items, key)
+	return entry.stmt, true
+}
+
+// Clear drops all the statements. Returns them to be closed by the caller.
+func (c *StmtCache[S]) Clear() []S {
+	dropped := make([]S, 0, c.ll.Len())
+	for el := c.ll.Front(); el != nil; el = el.Next() {
+		dropped = append(dropped, el.Value.(*stmtCacheEntry[S]).stmt)
+	}
+	c.ll.Init()
+	clear(c.items)
+	return dropped
+}
+
+func (c *StmtCache[S]) Len() int {
+	return c.ll.Len()
+}
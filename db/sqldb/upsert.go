@@ -0,0 +1,476 @@
+package sqldb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxPlaceholdersPerStmt is the max number of bind parameters in one statement (PostgreSQL & MySQL: 65535)
+const MaxPlaceholdersPerStmt = 65535
+
+// DefaultBulkBatchRows is the default max number of rows in one multi-row statement
+const DefaultBulkBatchRows = 500
+
+type UpsertResult struct {
+	Affected int64 // rows inserted or updated
+	Inserted int64
+	Updated  int64
+}
+
+// Upsert inserts rows into the table, updating updateColumns of the rows conflicting on conflictColumns.
+// Each row has the values of columns in the same order.
+// PostgreSQL: INSERT ... ON CONFLICT (conflictColumns) DO UPDATE SET col = EXCLUDED.col
+// MySQL: INSERT ... ON DUPLICATE KEY UPDATE col = VALUES(col)
+// [NOTE] MySQL checks every unique key, not only conflictColumns.
+// [NOTE] MySQL reports 1 per inserted row and 2 per updated row, so Inserted/Updated are derived assuming no row is left unchanged.
+// With no updateColumns, conflicting rows are left as they are.
+// Rows with the same conflictColumns values are deduped beforehand, the last one winning,
+// since PostgreSQL cannot affect a row twice in a statement. The counts are of the deduped rows.
+// Rows are sent in multi-row batches. When more than one batch is needed, all the batches run in a single transaction.
+func Upsert(
+	ctx context.Context,
+	dbClient Client,
+	table string,
+	columns []string,
+	conflictColumns []string,
+	updateColumns []string,
+	rows [][]any,
+) (*UpsertResult, error) {
+	if err := validateIdentifiers(table, columns, conflictColumns, updateColumns); err != nil {
+		return nil, err
+	}
+	if len(conflictColumns) == 0 {
+		return nil, errors.New("upsert: conflict columns required")
+	}
+	if err := validateRowWidths(rows, len(columns)); err != nil {
+		return nil, err
+	}
+	rows, err := dedupeRows(rows, columns, conflictColumns)
+	if err != nil {
+		return nil, err
+	}
+	result := &UpsertResult{}
+	if len(rows) == 0 {
+		return result, nil
+	}
+	dbType := dbClient.Conf().Type
+	err = runBatches(ctx, dbClient, rows, len(columns), func(ctx context.Context, q querier, batch [][]any) error {
+		args := flattenRows(batch)
+		valuesClause := valuesRowsClause(dbClient, len(batch), len(columns))
+		switch dbType {
+		case "pgsql":
+			sqlStmt := upsertSQLPostgres(table, columns, conflictColumns, updateColumns, valuesClause)
+			inserted, total, err := q.countInserted(ctx, sqlStmt, args...)
+			if err != nil {
+				return err
+			}
+			result.Affected += total
+			result.Inserted += inserted
+			result.Updated += total - inserted
+		case "mysql":
+			sqlStmt := upsertSQLMySQL(table, columns, conflictColumns, updateColumns, valuesClause)
+			res, err := q.exec(ctx, sqlStmt, args...)
+			if err != nil {
+				return err
+			}
+			affected, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			n := int64(len(batch))
+			updated := max(affected-n, 0)
+			result.Affected += affected - updated // an updated row counts 2
+			result.Inserted += max(affected-2*updated, 0)
+			result.Updated += updated
+		default:
+			return fmt.Errorf("upsert not supported for %s", dbType)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// BulkUpdate updates updateColumns of the rows matching keyColumns with different values per row in a single statement per batch.
+// Each row has the values of keyColumns followed by the values of updateColumns.
+// PostgreSQL: UPDATE t SET ... FROM (SELECT ... FROM t WHERE false UNION ALL VALUES ...) v WHERE ...
+// (The empty SELECT lends the column types of the table to the VALUES)
+// MySQL: UPDATE t JOIN (SELECT ? AS k, ... UNION ALL SELECT ...) v ON ... SET ...
+// Returns the number of rows affected
+func BulkUpdate(
+	ctx context.Context,
+	dbClient Client,
+	table string,
+	keyColumns []string,
+	updateColumns []string,
+	rows [][]any,
+) (int64, error) {
+	if err := validateIdentifiers(table, keyColumns, updateColumns); err != nil {
+		return 0, err
+	}
+	if len(keyColumns) == 0 || len(updateColumns) == 0 {
+		return 0, errors.New("bulk update: key columns and update columns required")
+	}
+	columns := append(append([]string(nil), keyColumns...), updateColumns...)
+	if err := validateRowWidths(rows, len(columns)); err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	dbType := dbClient.Conf().Type
+	var affected int64
+	err := runBatches(ctx, dbClient, rows, len(columns), func(ctx context.Context, q querier, batch [][]any) error {
+		var sqlStmt string
+		switch dbType {
+		case "pgsql":
+			sqlStmt = bulkUpdateSQLPostgres(table, keyColumns, updateColumns, valuesRowsClause(dbClient, len(batch), len(columns)))
+		case "mysql":
+			sqlStmt = bulkUpdateSQLMySQL(table, keyColumns, updateColumns, len(batch))
+		default:
+			return fmt.Errorf("bulk update not supported for %s", dbType)
+		}
+		res, err := q.exec(ctx, sqlStmt, flattenRows(batch)...)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		affected += n
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return affected, nil
+}
+
+func upsertSQLPostgres(table string, columns, conflictColumns, updateColumns []string, valuesClause string) string {
+	var b strings.Builder
+	b.WriteString("INSERT INTO ")
+	b.WriteString(table)
+	b.WriteString(" (")
+	b.WriteString(strings.Join(columns, ", "))
+	b.WriteString(") VALUES ")
+	b.WriteString(valuesClause)
+	b.WriteString(" ON CONFLICT (")
+	b.WriteString(strings.Join(conflictColumns, ", "))
+	if len(updateColumns) == 0 {
+		b.WriteString(") DO NOTHING")
+	} else {
+		b.WriteString(") DO UPDATE SET ")
+		for i, col := range updateColumns {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(col + " = EXCLUDED." + col)
+		}
+	}
+	// xmax = 0 only for the freshly inserted row versions
+	b.WriteString(" RETURNING (xmax = 0)::int")
+	return b.String()
+}
+
+func upsertSQLMySQL(table string, columns, conflictColumns, updateColumns []string, valuesClause string) string {
+	var b strings.Builder
+	b.WriteString("INSERT INTO ")
+	b.WriteString(table)
+	b.WriteString(" (")
+	b.WriteString(strings.Join(columns, ", "))
+	b.WriteString(") VALUES ")
+	b.WriteString(valuesClause)
+	b.WriteString(" ON DUPLICATE KEY UPDATE ")
+	if len(updateColumns) == 0 {
+		// no-op assignment: conflicting rows are left unchanged (0 rows affected)
+		b.WriteString(conflictColumns[0] + " = " + conflictColumns[0])
+		return b.String()
+	}
+	for i, col := range updateColumns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(col + " = VALUES(" + col + ")")
+	}
+	return b.String()
+}
+
+func bulkUpdateSQLPostgres(table string, keyColumns, updateColumns []string, valuesClause string) string {
+	columns := append(append([]string(nil), keyColumns...), updateColumns...)
+	var b strings.Builder
+	b.WriteString("UPDATE ")
+	b.WriteString(table)
+	b.WriteString(" AS u SET ")
+	for i, col := range updateColumns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(col + " = v." + col)
+	}
+	b.WriteString(" FROM (SELECT ")
+	b.WriteString(strings.Join(columns, ", "))
+	b.WriteString(" FROM ")
+	b.WriteString(table)
+	b.WriteString(" WHERE false UNION ALL VALUES ")
+	b.WriteString(valuesClause)
+	b.WriteString(") AS v WHERE ")
+	for i, col := range keyColumns {
+		if i > 0 {
+			b.WriteString(" AND ")
+		}
+		b.WriteString("u." + col + " = v." + col)
+	}
+	return b.String()
+}
+
+func bulkUpdateSQLMySQL(table string, keyColumns, updateColumns []string, rowCnt int) string {
+	columns := append(append([]string(nil), keyColumns...), updateColumns...)
+	var b strings.Builder
+	b.WriteString("UPDATE ")
+	b.WriteString(table)
+	b.WriteString(" AS u JOIN (")
+	for r := 0; r < rowCnt; r++ {
+		if r > 0 {
+			b.WriteString(" UNION ALL ")
+		}
+		b.WriteString("SELECT ")
+		for i, col := range columns {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteByte('?')
+			if r == 0 {
+				b.WriteString(" AS " + col)
+			}
+		}
+	}
+	b.WriteString(") AS v ON ")
+	for i, col := range keyColumns {
+		if i > 0 {
+			b.WriteString(" AND ")
+		}
+		b.WriteString("u." + col + " = v." + col)
+	}
+	b.WriteString(" SET ")
+	for i, col := range updateColumns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("u." + col + " = v." + col)
+	}
+	return b.String()
+}
+
+// valuesRowsClause generates "(p1,p2), (p3,p4), ..." numbering the placeholders across the rows
+func valuesRowsClause(dbClient Client, rowCnt int, colCnt int) string {
+	var b strings.Builder
+	b.Grow(rowCnt * colCnt * 4)
+	for r := 0; r < rowCnt; r++ {
+		if r > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		b.WriteString(dbClient.Placeholders(colCnt, r*colCnt+1))
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+func flattenRows(rows [][]any) []any {
+	if len(rows) == 0 {
+		return nil
+	}
+	args := make([]any, 0, len(rows)*len(rows[0]))
+	for _, row := range rows {
+		args = append(args, row...)
+	}
+	return args
+}
+
+// dedupeRows keeps the last of the rows with the same values of keyColumns, in the order of the kept rows.
+// Rows with a nil key value are all kept
+func dedupeRows(rows [][]any, columns []string, keyColumns []string) ([][]any, error) {
+	keyIdx := make([]int, len(keyColumns))
+	for i, key := range keyColumns {
+		keyIdx[i] = slices.Index(columns, key)
+		if keyIdx[i] == -1 {
+			return nil, fmt.Errorf("column %q not in the columns", key)
+		}
+	}
+	last := make(map[string]int, len(rows)) // row key -> index of its last row
+	keys := make([]string, len(rows))       // "" = never deduped
+	dup := false
+	for i, row := range rows {
+		keys[i] = rowKey(row, keyIdx)
+		if keys[i] == "" {
+			continue
+		}
+		_, seen := last[keys[i]]
+		dup = dup || seen
+		last[keys[i]] = i
+	}
+	if !dup {
+		return rows, nil
+	}
+	deduped := make([][]any, 0, len(rows))
+	for i, row := range rows {
+		if keys[i] == "" || last[keys[i]] == i {
+			deduped = append(deduped, row)
+		}
+	}
+	return deduped, nil
+}
+
+// rowKey joins the values of the row at keyIdx. "" if one is nil, since NULL never conflicts
+func rowKey(row []any, keyIdx []int) string {
+	var b strings.Builder
+	for _, idx := range keyIdx {
+		if row[idx] == nil {
+			return ""
+		}
+		_, _ = fmt.Fprintf(&b, "%#v\x00", keyValue(row[idx]))
+	}
+	return b.String()
+}
+
+// keyValue normalizes a value the DB compares equal regardless of its Go type:
+// integers to int64 (uint64 only above math.MaxInt64), []byte to string and times to UTC
+func keyValue(v any) any {
+	switch x := v.(type) {
+	case int:
+		return int64(x)
+	case int8:
+		return int64(x)
+	case int16:
+		return int64(x)
+	case int32:
+		return int64(x)
+	case uint:
+		return uintKeyValue(uint64(x))
+	case uint8:
+		return int64(x)
+	case uint16:
+		return int64(x)
+	case uint32:
+		return int64(x)
+	case uint64:
+		return uintKeyValue(x)
+	case []byte:
+		return string(x)
+	case time.Time:
+		return x.UTC()
+	}
+	return v
+}
+
+func uintKeyValue(x uint64) any {
+	if x <= math.MaxInt64 {
+		return int64(x)
+	}
+	return x
+}
+
+func validateIdentifiers(table string, columnLists ...[]string) error {
+	if !IdentifierRegexp.MatchString(table) {
+		return fmt.Errorf("invalid SQL identifier: %q", table)
+	}
+	for _, columns := range columnLists {
+		for _, col := range columns {
+			if !IdentifierRegexp.MatchString(col) {
+				return fmt.Errorf("invalid SQL identifier: %q", col)
+			}
+		}
+	}
+	return nil
+}
+
+func validateRowWidths(rows [][]any, width int) error {
+	if width == 0 {
+		return errors.New("columns required")
+	}
+	for i, row := range rows {
+		if len(row) != width {
+			return fmt.Errorf("row %d has %d values, expected %d", i, len(row), width)
+		}
+	}
+	return nil
+}
+
+// querier abstracts a Client and a Tx for the bulk helpers
+type querier struct {
+	execFn  func(ctx context.Context, query string, args ...any) (Result, error)
+	queryFn func(ctx context.Context, query string, args ...any) (Rows, error)
+}
+
+func (q querier) exec(ctx context.Context, query string, args ...any) (Result, error) {
+	return q.execFn(ctx, query, args...)
+}
+
+// countInserted runs a statement returning one int column per row (1 = inserted)
+// Returns (# of inserted rows, # of returned rows)
+func (q querier) countInserted(ctx context.Context, query string, args ...any) (int64, int64, error) {
+	rows, err := q.queryFn(ctx, query, args...)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("rows.Close() failed: %v", err)
+		}
+	}()
+	var inserted, total int64
+	for rows.Next() {
+		var flag int64
+		if err = rows.Scan(&flag); err != nil {
+			return 0, 0, fmt.Errorf("scan failed: %v", err)
+		}
+		inserted += flag
+		total++
+	}
+	if err = rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("error during iterating rows: %v", err)
+	}
+	return inserted, total, nil
+}
+
+// runBatches splits rows into batches within the placeholder limit
+// A single batch runs on the client. Multiple batches run in a transaction.
+func runBatches(
+	ctx context.Context,
+	dbClient Client,
+	rows [][]any,
+	width int,
+	runBatch func(ctx context.Context, q querier, batch [][]any) error,
+) error {
+	batchRows := min(DefaultBulkBatchRows, MaxPlaceholdersPerStmt/width)
+	if batchRows < 1 {
+		return fmt.Errorf("too many columns: %d", width)
+	}
+	if len(rows) <= batchRows {
+		return runBatch(ctx, querier{execFn: dbClient.Exec, queryFn: dbClient.QueryRows}, rows)
+	}
+	tx, err := dbClient.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	q := querier{execFn: tx.Exec, queryFn: tx.Query}
+	for start := 0; start < len(rows); start += batchRows {
+		end := min(start+batchRows, len(rows))
+		if err = runBatch(ctx, q, rows[start:end]); err != nil {
+			if rbErr := tx.Rollback(ctx); rbErr != nil {
+				log.Printf("[ERROR] rollback failed: %v", rbErr)
+			}
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -2,6 +2,7 @@ package framework
 
 import (
 	"context"
+	"io/fs"
 	"log"
 	"net/http"
 	"sync"
@@ -11,6 +12,7 @@ import (
 	"github.com/logitools/gw/clients"
 	"github.com/logitools/gw/db/kvdb"
 	"github.com/logitools/gw/db/sqldb"
+	"github.com/logitools/gw/outbox"
 	"github.com/logitools/gw/schedjobs"
 	"github.com/logitools/gw/storages"
 	"github.com/logitools/gw/svc"
@@ -19,6 +21,7 @@ import (
 	"github.com/logitools/gw/uds"
 	"github.com/logitools/gw/web"
 	"github.com/logitools/gw/web/cookiesession"
+	"github.com/logitools/gw/workqueue"
 )
 
 // Core - common config
@@ -34,6 +37,7 @@ type Core struct {
 	JobScheduler         *schedjobs.Scheduler                             `json:"-"`          // PrepareJobScheduler
 	WebService           *web.Service                                     `json:"-"`          // PrepareWebService
 	ThrottleBucketStore  *throttle.BucketStore                            `json:"-"`          // PrepareThrottleBucketStore
+	ClientQuotaStore     *throttle.QuotaStore                             `json:"-"`          // PrepareClientQuotas
 	VolatileKV           *sync.Map                                        `json:"-"`          // map[string]string
 	SessionLocks         *sync.Map                                        `json:"-"`          // map[string]*sync.Mutex for AccessTokenSessions and CookieSessions
 	ActionLocks          *sync.Map                                        `json:"-"`          // map[string]struct{}
@@ -43,10 +47,15 @@ type Core struct {
 	KVDBClient           kvdb.Client                                      `json:"-"`          // prepareKVDBClient
 	SQLDBConfs           map[string]*sqldb.Conf                           `json:"-"`          // loadSQLDBConfs
 	SQLDBClients         map[string]sqldb.Client                          `json:"-"`          // prepareSQLDBClients
+	SQLFS                fs.FS                                            `json:"-"`          // PrepareSQLDatabases. RawSQL source for ReloadSQLRawStore
 	ClientApps           atomic.Pointer[map[string]clients.ClientAppConf] `json:"-"`          // [Hot Reload] PrepareClientApps
 	CookieSessionManager *cookiesession.Manager                           `json:"-"`          // PrepareCookieSessions
 	HTMLTemplateStore    *tpl.HTMLTemplateStore                           `json:"-"`          // PrepareHTMLTemplateStore
 	MainBackendClient    *mainbackend.Client                              `json:"-"`          // PrepareMainBackendClient
+	OutboxStore          *outbox.Store                                    `json:"-"`          // PrepareOutbox
+	OutboxRelay          *outbox.Relay                                    `json:"-"`          // PrepareOutbox
+	WorkQueue            *workqueue.Queue                                 `json:"-"`          // PrepareWorkQueue
+	WorkQueuePool        *workqueue.Pool                                  `json:"-"`          // PrepareWorkQueue
 
 	services []svc.Service // Services to Manage
 	done     chan error
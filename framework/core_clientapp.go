@@ -2,10 +2,13 @@ package framework
 
 import (
 	"encoding/json/v2"
+	"errors"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/logitools/gw/clients"
+	"github.com/logitools/gw/throttle"
 )
 
 // PrepareClientApps prepares ClientApps
@@ -47,3 +50,14 @@ func (c *Core) GetClientAppConf(id string) (clients.ClientAppConf, bool) {
 	conf.ID = id
 	return conf, ok
 }
+
+// PrepareClientQuotas prepares the ClientQuotaStore counting the daily/monthly quotas of the client apps in the KV DB
+// keyPrefix defaults to throttle.DefaultQuotaKeyPrefix and location (the calendar of the periods) to UTC
+// Call after PrepareKVDatabase
+func (c *Core) PrepareClientQuotas(keyPrefix string, location *time.Location) error {
+	if c.KVDBClient == nil {
+		return errors.New("kv database not prepared")
+	}
+	c.ClientQuotaStore = throttle.NewQuotaStore(c.KVDBClient, keyPrefix, location)
+	return nil
+}
@@ -1,6 +1,13 @@
 package framework
 
 import (
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
 	"github.com/logitools/gw/schedjobs"
 )
 
@@ -8,3 +15,73 @@ func (c *Core) PrepareJobScheduler() {
 	c.JobScheduler = schedjobs.NewScheduler(c.RootCtx)
 	c.AddService(c.JobScheduler)
 }
+
+// PrepareJobStore loads config/.jobstore.json and persists the typed one-time jobs of the JobScheduler
+// Use after PrepareJobScheduler and the database preparations. Register the task types before StartServices
+func (c *Core) PrepareJobStore() error {
+	confFilePath := filepath.Join(c.AppRoot, "config", ".jobstore.json")
+	confBytes, err := os.ReadFile(confFilePath) // ([]byte, error)
+	if err != nil {
+		return err
+	}
+	var conf schedjobs.StoreConf
+	if err = json.Unmarshal(confBytes, &conf); err != nil {
+		return err
+	}
+	if c.JobScheduler == nil {
+		return errors.New("job scheduler not prepared")
+	}
+	switch conf.CatchUp {
+	case "", schedjobs.CatchUpRun, schedjobs.CatchUpSkip, schedjobs.CatchUpWithin:
+	default:
+		return fmt.Errorf("jobstore: unknown catch_up %q", conf.CatchUp)
+	}
+	var store schedjobs.JobStore
+	switch conf.Backend {
+	case "sql":
+		dbClient, ok := c.SQLDBClients[conf.DBName]
+		if !ok {
+			return fmt.Errorf("jobstore: sql database %q not found", conf.DBName)
+		}
+		if store, err = schedjobs.NewSQLJobStore(dbClient, conf.Table); err != nil {
+			return err
+		}
+	case "kv":
+		if c.KVDBClient == nil {
+			return errors.New("jobstore: kv database not prepared")
+		}
+		store = schedjobs.NewKVJobStore(c.KVDBClient, conf.KVKey)
+	default:
+		return fmt.Errorf("jobstore: unknown backend %q", conf.Backend)
+	}
+	c.JobScheduler.SetJobStore(store)
+	c.JobScheduler.CatchUp = conf.CatchUp
+	c.JobScheduler.CatchUpWindow = time.Duration(conf.CatchUpWindow) * time.Second
+	return nil
+}
+
+// PrepareJobLease loads config/.joblease.json and runs the jobs of the JobScheduler once across the instances
+// Use after PrepareJobScheduler and PrepareKVDatabase
+func (c *Core) PrepareJobLease() error {
+	confFilePath := filepath.Join(c.AppRoot, "config", ".joblease.json")
+	confBytes, err := os.ReadFile(confFilePath) // ([]byte, error)
+	if err != nil {
+		return err
+	}
+	var conf schedjobs.LeaseConf
+	if err = json.Unmarshal(confBytes, &conf); err != nil {
+		return err
+	}
+	if c.JobScheduler == nil {
+		return errors.New("job scheduler not prepared")
+	}
+	if c.KVDBClient == nil {
+		return errors.New("joblease: kv database not prepared")
+	}
+	lease := schedjobs.NewKVLease(c.KVDBClient, conf.KeyPrefix, conf.Node)
+	if err = c.JobScheduler.SetLease(lease, conf.Mode); err != nil {
+		return fmt.Errorf("joblease: %w", err)
+	}
+	c.JobScheduler.LeaderLeaseTTL = time.Duration(conf.TTL) * time.Second
+	return nil
+}
@@ -0,0 +1,35 @@
+package framework
+
+import (
+	"encoding/json/v2"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/logitools/gw/outbox"
+)
+
+// PrepareOutbox loads config/.outbox.json, prepares the OutboxStore and registers the OutboxRelay delivering to sink
+// Use after PrepareSQLDatabases
+func (c *Core) PrepareOutbox(sink outbox.Sink) error {
+	confFilePath := filepath.Join(c.AppRoot, "config", ".outbox.json")
+	confBytes, err := os.ReadFile(confFilePath) // ([]byte, error)
+	if err != nil {
+		return err
+	}
+	var conf outbox.Conf
+	if err = json.Unmarshal(confBytes, &conf); err != nil {
+		return err
+	}
+	dbClient, ok := c.SQLDBClients[conf.DBName]
+	if !ok {
+		return fmt.Errorf("outbox: sql database %q not found", conf.DBName)
+	}
+	c.OutboxStore, err = outbox.NewStore(dbClient, &conf)
+	if err != nil {
+		return err
+	}
+	c.OutboxRelay = outbox.NewRelay(c.RootCtx, c.OutboxStore, sink)
+	c.AddService(c.OutboxRelay)
+	return nil
+}
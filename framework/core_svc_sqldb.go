@@ -1,7 +1,9 @@
 package framework
 
 import (
+	"context"
 	"encoding/json/v2"
+	"fmt"
 	"io/fs"
 	"log"
 	"os"
@@ -51,17 +53,14 @@ func (c *Core) prepareSQLDBClients() error {
 }
 
 // PrepareSQLDatabases for SQL DB Clients & RawSQL Stores, etc
+// sqlFS is remembered as SQLFS for ReloadSQLRawStore
 func (c *Core) PrepareSQLDatabases(sqlFS fs.FS) error {
 	// Load SQL Databases Config File
 	err := c.loadSQLDBConfs()
 	if err != nil {
 		return err
 	}
-	DBTypesSet := make(map[string]struct{})
-	for _, conf := range c.SQLDBConfs {
-		DBTypesSet[conf.Type] = struct{}{}
-	}
-	if len(DBTypesSet) == 0 {
+	if len(c.SQLDBConfs) == 0 {
 		return nil
 	}
 
@@ -70,17 +69,61 @@ func (c *Core) PrepareSQLDatabases(sqlFS fs.FS) error {
 		return err
 	}
 
-	if _, ok := DBTypesSet["mysql"]; ok {
-		err = mysql.LoadRawStmtsToStore(sqlFS)
-		if err != nil {
-			return err
-		}
-	}
-	if _, ok := DBTypesSet["pgsql"]; ok {
-		err = pgsql.LoadRawStmtsToStore(sqlFS)
-		if err != nil {
-			return err
+	// Load RawSQL Stores (one per DB type, shared by the clients until reloaded)
+	c.SQLFS = sqlFS
+	stores := make(map[string]*sqldb.RawSQLStore)
+	for dbName, dbClient := range c.SQLDBClients {
+		dbType := dbClient.Conf().Type
+		store, ok := stores[dbType]
+		if !ok {
+			var report *sqldb.RawLoadReport
+			if store, report, err = sqldb.LoadRawSQLStore(sqlFS, dbType); err != nil {
+				return err
+			}
+			if err = report.Err(); err != nil {
+				return err
+			}
+			logRawLoadReport(report)
+			stores[dbType] = store
 		}
+		log.Printf("[INFO][SQLDB] %q raw stmt store set", dbName)
+		dbClient.SetRawSQLStore(store)
 	}
 	return nil
 }
+
+// ReloadSQLRawStore [Hot Reload] loads a new RawSQLStore for the client of dbName from sqlFS (SQLFS if nil)
+// and swaps it in only if every statement is valid.
+// With verify, the statements are also prepared against the live DB.
+func (c *Core) ReloadSQLRawStore(ctx context.Context, dbName string, sqlFS fs.FS, verify bool) (*sqldb.RawLoadReport, error) {
+	dbClient, ok := c.SQLDBClients[dbName]
+	if !ok {
+		return nil, fmt.Errorf("db client not found: %s", dbName)
+	}
+	if sqlFS == nil {
+		sqlFS = c.SQLFS
+	}
+	if sqlFS == nil {
+		return nil, fmt.Errorf("no sql fs to load from")
+	}
+	store, report, err := sqldb.LoadRawSQLStore(sqlFS, dbClient.Conf().Type)
+	if err != nil {
+		return nil, err
+	}
+	if verify && report.OK() {
+		sqldb.VerifyRawSQLStore(ctx, dbClient, store, report)
+	}
+	if err = report.Err(); err != nil {
+		return report, err
+	}
+	dbClient.SetRawSQLStore(store)
+	logRawLoadReport(report)
+	return report, nil
+}
+
+func logRawLoadReport(report *sqldb.RawLoadReport) {
+	log.Printf("[INFO][SQLDB][%s] %d sql raw stmts loaded", report.DBType, report.Loaded)
+	for _, key := range report.Shadowed {
+		log.Printf("[INFO][SQLDB][%s] %q: .sql shadowed by .%s", report.DBType, key, report.DBType)
+	}
+}
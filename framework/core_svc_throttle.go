@@ -1,12 +1,91 @@
 package framework
 
 import (
+	"encoding/json/v2"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/logitools/gw/throttle"
 )
 
+// Throttle setup, in this order and before the services start:
+//  1. PrepareThrottleBucketStore
+//  2. UseKVDBForThrottle (optional. after PrepareKVDatabase)
+//  3. LoadThrottleConf (after PrepareKVDatabase for snapshot_kv_key)
+//
+// The BucketStore is not safe to reconfigure once started, except by ReloadThrottleConf.
 func (c *Core) PrepareThrottleBucketStore(cleanupCycle time.Duration, cleanupOlderThan time.Duration) {
 	c.ThrottleBucketStore = throttle.NewBucketStore(c.RootCtx, cleanupCycle, cleanupOlderThan)
 	c.AddService(c.ThrottleBucketStore)
 }
+
+// UseKVDBForThrottle stores the buckets of the Distributed throttle bucket groups in the KV DB
+// Use after PrepareThrottleBucketStore and PrepareKVDatabase
+func (c *Core) UseKVDBForThrottle(keyPrefix string) {
+	c.ThrottleBucketStore.SetKVClient(c.KVDBClient, keyPrefix)
+}
+
+// LoadThrottleConf applies config/.throttle.json (bucket groups, route policies and snapshot) to the ThrottleBucketStore
+// Optional: without the file, nothing is applied. Use after PrepareThrottleBucketStore, and after UseKVDBForThrottle for distributed groups
+// With snapshot_file or snapshot_kv_key, the local buckets are saved on stop and restored on start
+func (c *Core) LoadThrottleConf() error {
+	if c.ThrottleBucketStore == nil {
+		return errors.New("LoadThrottleConf requires PrepareThrottleBucketStore")
+	}
+	conf, err := c.loadThrottleConf()
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Println("[INFO][Throttle] no config/.throttle.json")
+			return nil
+		}
+		return err
+	}
+	switch {
+	case conf.SnapshotKVKey != "":
+		if c.KVDBClient == nil {
+			return errors.New("throttle snapshot_kv_key requires PrepareKVDatabase")
+		}
+		c.ThrottleBucketStore.SetSnapshotStorage(&throttle.KVSnapshotStorage{
+			Client: c.KVDBClient,
+			Key:    conf.SnapshotKVKey,
+			TTL:    c.ThrottleBucketStore.CleanupOlderThan(), // older buckets are discarded anyway
+		})
+	case conf.SnapshotFile != "":
+		snapshotPath := conf.SnapshotFile
+		if !filepath.IsAbs(snapshotPath) {
+			snapshotPath = filepath.Join(c.AppRoot, snapshotPath)
+		}
+		c.ThrottleBucketStore.SetSnapshotStorage(&throttle.FileSnapshotStorage{Path: snapshotPath})
+	}
+	return c.ThrottleBucketStore.ApplyConf(conf)
+}
+
+// ReloadThrottleConf [Hot Reload] re-reads config/.throttle.json and swaps the bucket groups and route policies
+// Unchanged groups keep their buckets, and the groups set in code are kept. Snapshot settings are not reloaded.
+func (c *Core) ReloadThrottleConf() error {
+	if c.ThrottleBucketStore == nil {
+		return errors.New("ReloadThrottleConf requires PrepareThrottleBucketStore")
+	}
+	conf, err := c.loadThrottleConf()
+	if err != nil {
+		return err
+	}
+	return c.ThrottleBucketStore.ApplyConf(conf)
+}
+
+func (c *Core) loadThrottleConf() (*throttle.Conf, error) {
+	confFilePath := filepath.Join(c.AppRoot, "config", ".throttle.json")
+	confBytes, err := os.ReadFile(confFilePath) // ([]byte, error)
+	if err != nil {
+		return nil, err
+	}
+	var conf throttle.Conf
+	if err = json.Unmarshal(confBytes, &conf); err != nil {
+		return nil, err
+	}
+	return &conf, nil
+}
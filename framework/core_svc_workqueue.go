@@ -0,0 +1,31 @@
+package framework
+
+import (
+	"encoding/json/v2"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/logitools/gw/workqueue"
+)
+
+// PrepareWorkQueue loads config/.workqueue.json, prepares the WorkQueue and registers the WorkQueuePool running its tasks
+// Use after PrepareKVDatabase. Register the task handlers on WorkQueuePool before StartServices
+func (c *Core) PrepareWorkQueue() error {
+	confFilePath := filepath.Join(c.AppRoot, "config", ".workqueue.json")
+	confBytes, err := os.ReadFile(confFilePath) // ([]byte, error)
+	if err != nil {
+		return err
+	}
+	var conf workqueue.Conf
+	if err = json.Unmarshal(confBytes, &conf); err != nil {
+		return err
+	}
+	if c.KVDBClient == nil {
+		return errors.New("workqueue: kv database not prepared")
+	}
+	c.WorkQueue = workqueue.NewQueue(c.KVDBClient, &conf)
+	c.WorkQueuePool = workqueue.NewPool(c.RootCtx, c.WorkQueue)
+	c.AddService(c.WorkQueuePool)
+	return nil
+}
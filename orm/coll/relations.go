@@ -93,3 +93,67 @@ func LinkHasMany[
 		}
 	}
 }
+
+// LinkManyToMany connects ParentCollection-ChildCollection where Parents and Children are joined by a Pivot (join) table
+// PivotRows carry both keys (a Parent ID and a Child ID) and, optionally, extra pivot columns
+// RelationField (a Collection) is on the Parent
+// PivotField (optional, nil = skip) is on the Parent, mapping each linked Child ID to its pivot row
+// Each Parent's collection follows the iteration order of the children collection
+func LinkManyToMany[
+	PP model.Identifiable[PID],
+	PID comparable,
+	CP model.Identifiable[CID],
+	CID comparable,
+	V any, // pivot row
+](
+	parents *Collection[PP, PID],
+	children *Collection[CP, CID],
+	pivotRows []V,
+	pivotKeys func(V) (PID, CID), // (parent id, child id) of a pivot row
+	relationFieldPtr func(PP) **Collection[CP, CID], // on the parent
+	pivotFieldPtr func(PP) *map[CID]V, // on the parent. optional
+) {
+	pidsByCID := make(map[CID][]PID, len(pivotRows))
+	pivotsByPID := make(map[PID]map[CID]V, parents.Len())
+	for _, pivotRow := range pivotRows {
+		pid, cid := pivotKeys(pivotRow)
+		if _, ok := parents.itemsMap[pid]; !ok {
+			continue
+		}
+		pivots, ok := pivotsByPID[pid]
+		if !ok {
+			pivots = make(map[CID]V)
+			pivotsByPID[pid] = pivots
+		}
+		if _, dup := pivots[cid]; !dup {
+			pidsByCID[cid] = append(pidsByCID[cid], pid)
+		}
+		pivots[cid] = pivotRow
+	}
+	childCollGrpByPID := make(map[PID]*Collection[CP, CID], parents.Len())
+	children.ForEach(func(child CP) {
+		for _, pid := range pidsByCID[child.GetID()] {
+			childColl, ok := childCollGrpByPID[pid]
+			if !ok {
+				childColl = NewEmptyOrderedCollection[CP, CID]()
+				childCollGrpByPID[pid] = childColl
+			}
+			childColl.AddIfNew(child)
+		}
+	})
+	for pid, parent := range parents.itemsMap {
+		if childColl, ok := childCollGrpByPID[pid]; ok {
+			*relationFieldPtr(parent) = childColl
+		} else {
+			*relationFieldPtr(parent) = NewEmptyOrderedCollection[CP, CID]()
+		}
+		if pivotFieldPtr == nil {
+			continue
+		}
+		if pivots, ok := pivotsByPID[pid]; ok {
+			*pivotFieldPtr(parent) = pivots
+		} else {
+			*pivotFieldPtr(parent) = make(map[CID]V)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package outbox
+
+const (
+	DefaultTable           = "outbox_events"
+	DefaultPollInterval    = 1000    // milliseconds
+	DefaultBatchSize       = 100     // events claimed per relay batch
+	DefaultMaxAttempts     = 10      // then dead-lettered
+	DefaultRetryBaseDelay  = 1000    // milliseconds
+	DefaultRetryMaxDelay   = 3600000 // milliseconds
+	DefaultDeliveryTimeout = 30000   // milliseconds
+)
+
+// Conf is loaded from config/.outbox.json
+type Conf struct {
+	DBName          string `json:"db_name"`          // key of the SQL database in .sql-databases.json
+	Table           string `json:"table"`            // outbox table. Default: DefaultTable
+	WakeChannel     string `json:"wake_channel"`     // [pgsql only] LISTEN/NOTIFY channel to wake up the relay on commit. "" = polling only
+	PollInterval    int    `json:"poll_interval"`    // milliseconds
+	BatchSize       int    `json:"batch_size"`       // events claimed per relay batch
+	MaxAttempts     int    `json:"max_attempts"`     // delivery attempts before the event is dead-lettered
+	RetryBaseDelay  int    `json:"retry_base_delay"` // milliseconds. doubled per failed attempt
+	RetryMaxDelay   int    `json:"retry_max_delay"`  // milliseconds. upper bound of the retry delay
+	DeliveryTimeout int    `json:"delivery_timeout"` // milliseconds. per delivery attempt
+}
+
+// SetDefaults fills the zero fields with the defaults
+func (c *Conf) SetDefaults() {
+	if c.Table == "" {
+		c.Table = DefaultTable
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = DefaultPollInterval
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = DefaultBatchSize
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = DefaultMaxAttempts
+	}
+	if c.RetryBaseDelay <= 0 {
+		c.RetryBaseDelay = DefaultRetryBaseDelay
+	}
+	if c.RetryMaxDelay <= 0 {
+		c.RetryMaxDelay = DefaultRetryMaxDelay
+	}
+	if c.DeliveryTimeout <= 0 {
+		c.DeliveryTimeout = DefaultDeliveryTimeout
+	}
+}
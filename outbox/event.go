@@ -0,0 +1,22 @@
+package outbox
+
+import "time"
+
+// Event statuses stored in the status column
+const (
+	StatusPending   = 0
+	StatusDelivered = 1
+	StatusDead      = 2 // dead-lettered after Conf.MaxAttempts failed deliveries
+)
+
+type Event struct {
+	ID        int64
+	Topic     string
+	Payload   string
+	Attempts  int // failed delivery attempts so far
+	CreatedAt time.Time
+}
+
+func (e *Event) FieldsToScan() []any {
+	return []any{&e.ID, &e.Topic, &e.Payload, &e.Attempts, &e.CreatedAt}
+}
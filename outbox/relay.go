@@ -0,0 +1,159 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/logitools/gw/db/sqldb"
+	"github.com/logitools/gw/svc"
+)
+
+// Relay is a service that delivers the pending outbox events to the Sink.
+// It polls every Conf.PollInterval, and on PostgreSQL with Conf.WakeChannel also wakes up on LISTEN notifications.
+// Events are claimed with FOR UPDATE SKIP LOCKED and leased while delivered, so multiple relay instances can share the table.
+type Relay struct {
+	Ctx    context.Context    // Service Context
+	cancel context.CancelFunc // Service Context CancelFunc
+	state  int                // internal service state
+	done   chan error         // Shutdown Error Channel
+	store  *Store
+	sink   Sink
+}
+
+func (r *Relay) Name() string {
+	return "OutboxRelay"
+}
+
+func NewRelay(parentCtx context.Context, store *Store, sink Sink) *Relay {
+	svcCtx, svcCancel := context.WithCancel(parentCtx)
+	return &Relay{
+		Ctx:    svcCtx,
+		cancel: svcCancel,
+		state:  svc.StateREADY,
+		done:   make(chan error, 1),
+		store:  store,
+		sink:   sink,
+	}
+}
+
+func (r *Relay) Start() error {
+	if r.state == svc.StateRUNNING {
+		return fmt.Errorf("already started")
+	}
+	if r.state != svc.StateREADY {
+		return fmt.Errorf("cannot start. not ready")
+	}
+	r.state = svc.StateRUNNING
+	log.Printf("[INFO][Outbox] relay started table=%s poll=%dms", r.store.conf.Table, r.store.conf.PollInterval)
+	go r.run()
+	return nil
+}
+
+func (r *Relay) Stop() {
+	if r.state != svc.StateRUNNING {
+		log.Println("[ERROR][Outbox] cannot stop. not running")
+		return
+	}
+	r.cancel()
+	r.state = svc.StateSTOPPED
+	log.Println("[INFO][Outbox] service stopped")
+}
+
+func (r *Relay) Done() <-chan error {
+	return r.done
+}
+
+func (r *Relay) run() {
+	var wakeCh <-chan sqldb.Notification // nil blocks forever: polling only
+	if r.store.notifySQL != "" {
+		ch, err := r.store.dbClient.Listen(r.Ctx, r.store.conf.WakeChannel)
+		if err != nil {
+			log.Printf("[WARN][Outbox] listen %q failed, polling only: %v", r.store.conf.WakeChannel, err)
+		} else {
+			wakeCh = ch
+		}
+	}
+	ticker := time.NewTicker(time.Duration(r.store.conf.PollInterval) * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		r.relayPending()
+		select {
+		case <-r.Ctx.Done():
+			log.Println("[INFO][Outbox] stopping relay service")
+			r.done <- nil
+			return
+		case <-ticker.C:
+		case _, ok := <-wakeCh:
+			if !ok {
+				wakeCh = nil
+			}
+		}
+	}
+}
+
+// relayPending relays batches until no due event is left
+func (r *Relay) relayPending() {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("[PANIC] recovered in outbox relay service: %v", rec)
+		}
+	}()
+	for r.Ctx.Err() == nil {
+		n, err := r.RelayBatch(r.Ctx)
+		if err != nil {
+			log.Printf("[ERROR][Outbox] relay batch: %v", err)
+			return
+		}
+		if n < r.store.conf.BatchSize {
+			return
+		}
+	}
+}
+
+// RelayBatch claims up to Conf.BatchSize due events, delivers them in id order and records the outcomes.
+// The events are claimed in a short transaction and delivered after it commits, so a slow sink holds no row lock.
+// Each delivery is bounded by Conf.DeliveryTimeout. The events are leased for two delivery timeouts,
+// and the lease of the undelivered ones is extended before a delivery could outlive it. Returns the number of claimed events.
+func (r *Relay) RelayBatch(ctx context.Context) (int, error) {
+	deliveryTimeout := time.Duration(r.store.conf.DeliveryTimeout) * time.Millisecond
+	lease := 2 * deliveryTimeout // a delivery, plus as long again for recording its outcome
+	now := time.Now()
+	leaseUntil := now.Add(lease)
+	events, err := r.store.claim(ctx, now, leaseUntil)
+	if err != nil {
+		return 0, err
+	}
+	markCtx := context.WithoutCancel(ctx) // record the outcome of a delivery even while stopping
+	for i, ev := range events {
+		if ctx.Err() != nil {
+			break // the rest become due again when the lease expires
+		}
+		if now = time.Now(); now.Add(deliveryTimeout + deliveryTimeout/2).After(leaseUntil) {
+			leaseUntil = now.Add(lease)
+			if err = r.store.extendLease(ctx, events[i:], leaseUntil); err != nil {
+				return 0, err
+			}
+		}
+		deliveryCtx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+		deliveryErr := r.sink.Deliver(deliveryCtx, ev)
+		cancel()
+		if deliveryErr != nil {
+			dead, err := r.store.markFailed(markCtx, ev, deliveryErr, time.Now())
+			if err != nil {
+				return 0, err
+			}
+			if dead {
+				log.Printf("[ERROR][Outbox] event %d (%s) dead-lettered after %d attempts: %v", ev.ID, ev.Topic, ev.Attempts+1, deliveryErr)
+			} else {
+				log.Printf("[WARN][Outbox] event %d (%s) attempt %d failed: %v", ev.ID, ev.Topic, ev.Attempts+1, deliveryErr)
+			}
+			continue
+		}
+		if err = r.store.markDelivered(markCtx, ev, time.Now()); err != nil {
+			return 0, err
+		}
+	}
+	return len(events), nil
+}
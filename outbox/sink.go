@@ -0,0 +1,90 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/logitools/gw/db/kvdb"
+)
+
+// Sink delivers an event. A non-nil error schedules a retry.
+// ctx is done after Conf.DeliveryTimeout.
+// Delivery is at-least-once: the same event may be delivered again (e.g. crash after delivery, before the outcome is recorded),
+// so consumers should dedupe by Event.ID
+type Sink interface {
+	Deliver(ctx context.Context, ev *Event) error
+}
+
+// SinkFunc adapts an in-process handler to Sink
+type SinkFunc func(ctx context.Context, ev *Event) error
+
+func (f SinkFunc) Deliver(ctx context.Context, ev *Event) error {
+	return f(ctx, ev)
+}
+
+// TopicSink dispatches events to in-process handlers by topic.
+// Events of a topic without a handler fail, and end up dead-lettered.
+type TopicSink map[string]SinkFunc
+
+func (t TopicSink) Deliver(ctx context.Context, ev *Event) error {
+	handler, ok := t[ev.Topic]
+	if !ok {
+		return fmt.Errorf("no handler for topic %q", ev.Topic)
+	}
+	return handler(ctx, ev)
+}
+
+// KVPubSubSink publishes the payload to the KV DB channel ChannelPrefix + topic
+type KVPubSubSink struct {
+	KVDBClient    kvdb.Client
+	ChannelPrefix string
+}
+
+func (s *KVPubSubSink) Deliver(ctx context.Context, ev *Event) error {
+	_, err := s.KVDBClient.Publish(ctx, s.ChannelPrefix+ev.Topic, ev.Payload)
+	return err
+}
+
+// WebhookSink POSTs the payload to URL. Any non-2xx response fails the delivery.
+// The event id and topic are sent in the X-Outbox-Event-Id and X-Outbox-Topic headers
+type WebhookSink struct {
+	HttpClient  *http.Client
+	URL         string
+	ContentType string            // Default: application/json
+	Headers     map[string]string // extra headers. e.g. Authorization
+}
+
+func (s *WebhookSink) Deliver(ctx context.Context, ev *Event) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, strings.NewReader(ev.Payload))
+	if err != nil {
+		return err
+	}
+	contentType := s.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Outbox-Event-Id", strconv.FormatInt(ev.ID, 10))
+	req.Header.Set("X-Outbox-Topic", ev.Topic)
+	for k, v := range s.Headers {
+		req.Header.Set(k, v)
+	}
+	httpClient := s.HttpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10)) // drain to reuse the connection
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s: %s", s.URL, res.Status)
+	}
+	return nil
+}
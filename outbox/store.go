@@ -0,0 +1,243 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/logitools/gw/db/sqldb"
+)
+
+// Store writes events into the outbox table.
+//
+// PostgreSQL:
+//
+//	CREATE TABLE outbox_events (
+//	  id BIGSERIAL PRIMARY KEY,
+//	  topic VARCHAR(255) NOT NULL,
+//	  payload TEXT NOT NULL,
+//	  status SMALLINT NOT NULL DEFAULT 0,
+//	  attempts INT NOT NULL DEFAULT 0,
+//	  next_attempt_at TIMESTAMPTZ NOT NULL,
+//	  last_error TEXT NOT NULL DEFAULT '',
+//	  created_at TIMESTAMPTZ NOT NULL,
+//	  delivered_at TIMESTAMPTZ NULL
+//	);
+//	CREATE INDEX idx_outbox_events_pending ON outbox_events (status, next_attempt_at, id);
+//
+// MySQL (8.0+ for SKIP LOCKED):
+//
+//	CREATE TABLE outbox_events (
+//	  id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+//	  topic VARCHAR(255) NOT NULL,
+//	  payload TEXT NOT NULL,
+//	  status SMALLINT NOT NULL DEFAULT 0,
+//	  attempts INT NOT NULL DEFAULT 0,
+//	  next_attempt_at DATETIME(6) NOT NULL,
+//	  last_error TEXT NOT NULL,
+//	  created_at DATETIME(6) NOT NULL,
+//	  delivered_at DATETIME(6) NULL,
+//	  KEY idx_outbox_events_pending (status, next_attempt_at, id)
+//	);
+//
+// Delivered rows are never deleted here. Purge them periodically. e.g. with a cron job
+type Store struct {
+	dbClient sqldb.Client
+	conf     *Conf
+	// statements with the placeholders of the db type
+	insertSQL    string
+	notifySQL    string // "" if no wake-up notification
+	claimSQL     string
+	deliveredSQL string
+	failedSQL    string
+	deadSQL      string
+	requeueSQL   string
+}
+
+func NewStore(dbClient sqldb.Client, conf *Conf) (*Store, error) {
+	conf.SetDefaults()
+	if !sqldb.IdentifierRegexp.MatchString(conf.Table) {
+		return nil, fmt.Errorf("invalid outbox table: %q", conf.Table)
+	}
+	dbType := dbClient.Conf().Type
+	if dbType != "pgsql" && dbType != "mysql" {
+		return nil, fmt.Errorf("outbox not supported for %s", dbType)
+	}
+	prefix := sqldb.PlaceholderPrefixForDBType[dbType]
+	columns := "id, topic, payload, attempts, created_at"
+	s := &Store{
+		dbClient: dbClient,
+		conf:     conf,
+		insertSQL: fmt.Sprintf(
+			"INSERT INTO %s (topic, payload, next_attempt_at, last_error, created_at) VALUES (?, ?, ?, '', ?)",
+			conf.Table,
+		),
+		claimSQL: fmt.Sprintf(
+			"SELECT %s FROM %s WHERE status = %d AND next_attempt_at <= ? ORDER BY id LIMIT %d FOR UPDATE SKIP LOCKED",
+			columns, conf.Table, StatusPending, conf.BatchSize,
+		),
+		deliveredSQL: fmt.Sprintf(
+			"UPDATE %s SET status = %d, attempts = ?, delivered_at = ?, last_error = '' WHERE id = ?",
+			conf.Table, StatusDelivered,
+		),
+		failedSQL: fmt.Sprintf(
+			"UPDATE %s SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?",
+			conf.Table,
+		),
+		deadSQL: fmt.Sprintf(
+			"SELECT %s FROM %s WHERE status = %d ORDER BY id LIMIT ?",
+			columns, conf.Table, StatusDead,
+		),
+		requeueSQL: fmt.Sprintf(
+			"UPDATE %s SET status = %d, attempts = 0, next_attempt_at = ?, last_error = '' WHERE id = ? AND status = %d",
+			conf.Table, StatusPending, StatusDead,
+		),
+	}
+	if conf.WakeChannel != "" && dbType == "pgsql" {
+		s.notifySQL = "SELECT pg_notify(?, ?)"
+	}
+	for _, p := range []*string{&s.insertSQL, &s.notifySQL, &s.claimSQL, &s.deliveredSQL, &s.failedSQL, &s.deadSQL, &s.requeueSQL} {
+		*p = sqldb.ReplaceStaticPlaceholders(*p, prefix)
+	}
+	return s, nil
+}
+
+func (s *Store) Conf() *Conf {
+	return s.conf
+}
+
+func (s *Store) DBClient() sqldb.Client {
+	return s.dbClient
+}
+
+// Add inserts an event inside the caller's transaction.
+// The event becomes visible to the relay only when tx commits, and is discarded on rollback.
+// On PostgreSQL with Conf.WakeChannel, the relay is woken up on commit.
+func (s *Store) Add(ctx context.Context, tx sqldb.Tx, topic string, payload string) error {
+	if topic == "" {
+		return errors.New("outbox: empty topic")
+	}
+	now := time.Now()
+	if _, err := tx.Exec(ctx, s.insertSQL, topic, payload, now, now); err != nil {
+		return err
+	}
+	if s.notifySQL != "" {
+		// NOTIFY is transactional: delivered on commit, dropped on rollback
+		if _, err := tx.Exec(ctx, s.notifySQL, s.conf.WakeChannel, topic); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeadLetters returns up to limit dead-lettered events, oldest first
+func (s *Store) DeadLetters(ctx context.Context, limit int) ([]*Event, error) {
+	return sqldb.RawQueryItems[Event, *Event](ctx, s.dbClient, s.deadSQL, limit)
+}
+
+// Requeue moves a dead-lettered event back to pending with its attempts reset.
+// Returns false if no dead-lettered event has the id.
+func (s *Store) Requeue(ctx context.Context, id int64) (bool, error) {
+	result, err := s.dbClient.Exec(ctx, s.requeueSQL, time.Now(), id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// claim locks the due pending events in a short transaction and leases them until leaseUntil,
+// by moving their next_attempt_at, so that the other relay instances skip them while they are delivered.
+// Rows locked by another relay instance are skipped. Leased events not marked in time become due again.
+func (s *Store) claim(ctx context.Context, now time.Time, leaseUntil time.Time) (events []*Event, err error) {
+	tx, err := s.dbClient.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(context.WithoutCancel(ctx)); rbErr != nil {
+				log.Printf("[ERROR][Outbox] rollback failed: %v", rbErr)
+			}
+		}
+	}()
+	rows, err := tx.Query(ctx, s.claimSQL, now)
+	if err != nil {
+		return nil, err
+	}
+	events, err = sqldb.ScanRowsToItems[Event, *Event](rows)
+	if closeErr := rows.Close(); closeErr != nil {
+		log.Printf("rows.Close() failed: %v", closeErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, tx.Commit(ctx)
+	}
+	leaseSQL, args := s.leaseStmt(events, leaseUntil)
+	if _, err = tx.Exec(ctx, leaseSQL, args...); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// extendLease moves the lease of the claimed events still pending to leaseUntil
+func (s *Store) extendLease(ctx context.Context, events []*Event, leaseUntil time.Time) error {
+	leaseSQL, args := s.leaseStmt(events, leaseUntil)
+	_, err := s.dbClient.Exec(ctx, leaseSQL, args...)
+	return err
+}
+
+// leaseStmt returns the statement setting next_attempt_at of the pending events to leaseUntil, and its arguments
+func (s *Store) leaseStmt(events []*Event, leaseUntil time.Time) (string, []any) {
+	args := make([]any, 0, len(events)+1)
+	args = append(args, leaseUntil)
+	for _, ev := range events {
+		args = append(args, ev.ID)
+	}
+	leaseSQL := fmt.Sprintf(
+		"UPDATE %s SET next_attempt_at = %s WHERE status = %d AND id IN (%s)",
+		s.conf.Table, s.dbClient.SinglePlaceholder(1), StatusPending, s.dbClient.Placeholders(len(events), 2),
+	)
+	return leaseSQL, args
+}
+
+func (s *Store) markDelivered(ctx context.Context, ev *Event, now time.Time) error {
+	_, err := s.dbClient.Exec(ctx, s.deliveredSQL, ev.Attempts+1, now, ev.ID)
+	return err
+}
+
+// markFailed schedules the next attempt with an exponential backoff, or dead-letters the event after Conf.MaxAttempts.
+// Returns true if the event was dead-lettered.
+func (s *Store) markFailed(ctx context.Context, ev *Event, deliveryErr error, now time.Time) (bool, error) {
+	attempts := ev.Attempts + 1
+	status := StatusPending
+	if attempts >= s.conf.MaxAttempts {
+		status = StatusDead
+	}
+	nextAttemptAt := now.Add(s.retryDelay(attempts))
+	_, err := s.dbClient.Exec(ctx, s.failedSQL, status, attempts, nextAttemptAt, deliveryErr.Error(), ev.ID)
+	return status == StatusDead, err
+}
+
+// retryDelay returns RetryBaseDelay * 2^(attempts-1), capped by RetryMaxDelay
+func (s *Store) retryDelay(attempts int) time.Duration {
+	maxDelay := time.Duration(s.conf.RetryMaxDelay) * time.Millisecond
+	delay := time.Duration(s.conf.RetryBaseDelay) * time.Millisecond
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	return min(delay, maxDelay)
+}
@@ -3,5 +3,8 @@ package reason
 const (
 	AccessTokenExpired = 1000
 	PermissionDenied   = 1002
+	RateLimited        = 1003 // Retry-After header tells when to retry
+	TooManyInFlight    = 1004 // concurrency limit. retry once a pending request completes
+	QuotaExceeded      = 1005 // daily or monthly quota of the client app. Retry-After tells when the period resets
 	Unknown            = 9999
 )
@@ -0,0 +1,147 @@
+package schedjobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+type fencingTokenKey struct{}
+
+// FencingTokenFromContext returns the fencing token of the lease the running task holds.
+// Pass it along with the writes so that the resource rejects tokens lower than the last one seen.
+// ok = false if the scheduler has no Lease or the job runs without one
+func FencingTokenFromContext(ctx context.Context) (int64, bool) {
+	token, ok := ctx.Value(fencingTokenKey{}).(int64)
+	return token, ok
+}
+
+// LeaderInfo describes the lease state of the scheduler
+type LeaderInfo struct {
+	Mode     string `json:"mode"`      // LeaseXXX. "" = no Lease
+	Node     string `json:"node"`      // owner of this instance
+	Leader   string `json:"leader"`    // [LeaseLeader] current holder of the leader lease. "" = none
+	Token    int64  `json:"token"`     // [LeaseLeader] fencing token of the current leader
+	IsLeader bool   `json:"is_leader"` // [LeaseLeader] this instance is the leader
+}
+
+// SetLease runs each cron run and persisted one-time job once across the instances sharing the lease.
+// mode is LeasePerRun or LeaseLeader. Call before Start
+func (s *Scheduler) SetLease(lease Lease, mode string) error {
+	switch mode {
+	case LeasePerRun, LeaseLeader:
+	default:
+		return fmt.Errorf("unknown lease mode %q", mode)
+	}
+	s.lease = lease
+	s.leaseMode = mode
+	return nil
+}
+
+// Leader returns the lease state. Queries the lease holder in the LeaseLeader mode
+func (s *Scheduler) Leader(ctx context.Context) (LeaderInfo, error) {
+	if s.lease == nil {
+		return LeaderInfo{}, nil
+	}
+	info := LeaderInfo{Mode: s.leaseMode, Node: s.lease.Owner()}
+	if s.leaseMode != LeaseLeader {
+		return info, nil
+	}
+	info.IsLeader = s.leaderToken.Load() > 0
+	owner, token, found, err := s.lease.Holder(ctx, leaderLeaseName)
+	if err != nil || !found {
+		return info, err
+	}
+	info.Leader, info.Token = owner, token
+	return info, nil
+}
+
+// claimCronRun decides whether this instance runs the job due at.
+// Returns the fencing token (0 = no Lease). A failing lease skips the run rather than risking a duplicate run
+func (s *Scheduler) claimCronRun(job *CronJob, at time.Time) (int64, bool) {
+	if s.lease == nil {
+		return 0, true
+	}
+	if s.leaseMode == LeaseLeader {
+		token := s.leaderToken.Load()
+		return token, token > 0
+	}
+	due := strconv.FormatInt(at.Unix(), 10)
+	return s.acquireRunLease("cron:"+job.ID+":"+due, "cron:"+job.ID)
+}
+
+// claimOneTimeRun decides whether this instance runs the job. Only the persisted jobs are shared by the instances
+func (s *Scheduler) claimOneTimeRun(job *OneTimeJob) (int64, bool) {
+	if s.lease == nil || s.store == nil || job.Type == "" {
+		return 0, true
+	}
+	execTime := strconv.FormatInt(job.ExecTime.Unix(), 10)
+	return s.acquireRunLease("once:"+job.ID+":"+execTime, "once:"+job.ID)
+}
+
+// acquireRunLease takes a lease left to expire, so that a late wake-up of another instance cannot take it again
+func (s *Scheduler) acquireRunLease(name string, fence string) (int64, bool) {
+	ctx, cancel := context.WithTimeout(s.Ctx, leaseCallTimeout)
+	defer cancel()
+	token, ok, err := s.lease.Acquire(ctx, name, fence, runLeaseTTL)
+	if err != nil {
+		log.Printf("[ERROR][Scheduler] failed to acquire lease %s. skipped: %v", name, err)
+		return 0, false
+	}
+	return token, ok
+}
+
+// runLeaderElection acquires and renews the leader lease every third of LeaderLeaseTTL until the service stops
+func (s *Scheduler) runLeaderElection() {
+	ttl := s.LeaderLeaseTTL
+	if ttl <= 0 {
+		ttl = DefaultLeaderLeaseTTL
+	}
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	for {
+		s.electLeader(ttl)
+		select {
+		case <-s.Ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) electLeader(ttl time.Duration) {
+	ctx, cancel := context.WithTimeout(s.Ctx, leaseCallTimeout)
+	defer cancel()
+	if token := s.leaderToken.Load(); token > 0 {
+		renewed, err := s.lease.Renew(ctx, leaderLeaseName, token, ttl)
+		if err == nil && renewed {
+			return
+		}
+		s.leaderToken.Store(0)
+		log.Printf("[WARN][Scheduler] leadership lost (token %d): %v", token, err)
+	}
+	token, ok, err := s.lease.Acquire(ctx, leaderLeaseName, leaderLeaseName, ttl)
+	if err != nil {
+		log.Printf("[ERROR][Scheduler] failed to acquire the leader lease: %v", err)
+		return
+	}
+	if ok {
+		s.leaderToken.Store(token)
+		log.Printf("[INFO][Scheduler] became the leader as %s (token %d)", s.lease.Owner(), token)
+	}
+}
+
+// resignLeader releases the leader lease on shutdown so that another instance takes over without waiting for the TTL
+func (s *Scheduler) resignLeader() {
+	token := s.leaderToken.Swap(0)
+	if s.lease == nil || token == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(s.Ctx), leaseCallTimeout)
+	defer cancel()
+	if err := s.lease.Release(ctx, leaderLeaseName, token); err != nil {
+		log.Printf("[ERROR][Scheduler] failed to release the leader lease: %v", err)
+	}
+}
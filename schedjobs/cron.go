@@ -1,15 +1,30 @@
 package schedjobs
 
+import (
+	"context"
+	"time"
+)
+
 type CronJob struct {
-	ID          string
-	Minutes     uint64 // 60 bits
-	Hours       uint32 // 24 bits
-	DaysOfMonth uint32 // 31 bits
-	Weekdays    uint8  // 7 bits
-	Task        func() error
+	ID           string
+	Spec         string                          // cron expression if built by ParseCron. for display
+	Every        time.Duration                   // interval job if > 0: runs at the multiples of Every since the zero time. the fields below are ignored
+	Seconds      uint64                          // 60 bits. 0 = at second 0 only
+	Minutes      uint64                          // 60 bits
+	Hours        uint32                          // 24 bits
+	DaysOfMonth  uint32                          // 31 bits
+	Months       uint16                          // 12 bits. 0 = every month
+	Weekdays     uint8                           // 7 bits
+	DayOrWeekday bool                            // POSIX rule: a day matching either DaysOfMonth or Weekdays runs the job. false = both must match
+	Location     *time.Location                  // wall clock zone of the fields. nil = the scheduler's Location. See the DST policies in location.go
+	Task         func(ctx context.Context) error // ctx is canceled on Timeout or after the scheduler's ShutdownGrace
+	Timeout      time.Duration                   // per run. 0 = no timeout
+	Retry        RetryPolicy                     // per run. zero = no retry
+	Overlap      int                             // OverlapXXX, when due while the previous run is still running
 	// Job-specific callbacks
 	OnAdded    func()
-	OnFinished func(error)
+	OnFinished func(err error, attempts int)
+	OnRetry    func(attempt int, err error, delay time.Duration) // before waiting for the next attempt
 }
 
 // NewEveryMinEmptyCronJob provides a cronjob matching every minute without a task as a template
@@ -20,17 +35,24 @@ func NewEveryMinEmptyCronJob(jobID string) *CronJob {
 		Minutes:     AllMinutes,
 		Hours:       AllHours,
 		DaysOfMonth: AllDaysOfMonth,
+		Months:      AllMonths,
 		Weekdays:    AllWeekdays,
 	}
 }
 
 const (
+	AllSeconds     uint64 = 0xFFFFFFFFFFFFFFF // 60 bits set
 	AllMinutes     uint64 = 0xFFFFFFFFFFFFFFF // 60 bits set
 	AllHours       uint32 = 0xFFFFFF          // 24 bits set
 	AllWeekdays    uint8  = 0b01111111        // sun:0b00000001, mon:0b00000010, ..., fri:0b00100000, sat:0b01000000
 	AllDaysOfMonth uint32 = 0x7FFFFFFF        // 31 bits set
+	AllMonths      uint16 = 0xFFF             // 12 bits set. jan:0b1, feb:0b10, ..., dec:0b100000000000
 )
 
+func BitsFromSeconds(list []int) uint64 {
+	return BitsFromMinutes(list) // same range 0-59
+}
+
 func BitsFromMinutes(list []int) uint64 {
 	var bits uint64
 	for _, v := range list {
@@ -70,3 +92,13 @@ func BitsFromDaysOfMonth(list []int) uint32 {
 	}
 	return bits
 }
+
+func BitsFromMonths(list []int) uint16 {
+	var bits uint16
+	for _, v := range list {
+		if v >= 1 && v <= 12 { // month 1 = bit 0
+			bits |= 1 << (v - 1)
+		}
+	}
+	return bits
+}
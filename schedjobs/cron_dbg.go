@@ -7,11 +7,28 @@ import (
 	"time"
 )
 
+// Matches reports whether the job is due at the second of now, on the wall clock of its Location if set.
+// The second pass of a repeated hour never matches, so that the job runs once. An interval job never matches.
 func (job *CronJob) Matches(now time.Time) bool {
+	if job.Location != nil {
+		now = now.In(job.Location)
+	}
 	log.Printf("[DEBUG] Checking match for %s at %v", job.ID, now)
-	log.Printf("[DEBUG] Cron spec: Minutes=%v Hours=%v DaysOfMonth=%v Weekdays=%v",
-		job.Minutes, job.Hours, job.DaysOfMonth, job.Weekdays,
+	log.Printf("[DEBUG] Cron spec: %q Seconds=%v Minutes=%v Hours=%v DaysOfMonth=%v Months=%v Weekdays=%v DayOrWeekday=%v",
+		job.Spec, job.Seconds, job.Minutes, job.Hours, job.DaysOfMonth, job.Months, job.Weekdays, job.DayOrWeekday,
 	)
+	if job.Every > 0 {
+		log.Println("[DEBUG] Interval job. no fields to match")
+		return false
+	}
+	if _, repeated := repeatedWallClock(now); repeated {
+		log.Println("[DEBUG] Repeated hour. already matched in the first pass")
+		return false
+	}
+	if !job.secondMatches(now) {
+		log.Println("[DEBUG] Second mismatch")
+		return false
+	}
 	if (job.Minutes & (1 << now.Minute())) == 0 {
 		log.Println("[DEBUG] Minute mismatch")
 		return false
@@ -20,12 +37,12 @@ func (job *CronJob) Matches(now time.Time) bool {
 		log.Println("[DEBUG] Hour mismatch")
 		return false
 	}
-	if (job.DaysOfMonth & (1 << (now.Day() - 1))) == 0 { // now.Day() = 1..31 -> bit 0 = day 1
-		log.Println("[DEBUG] Days of month mismatch")
+	if !job.monthMatches(now) {
+		log.Println("[DEBUG] Month mismatch")
 		return false
 	}
-	if (job.Weekdays & (1 << now.Weekday())) == 0 {
-		log.Println("[DEBUG] weekday mismatch")
+	if !job.dayMatches(now) {
+		log.Println("[DEBUG] Days of month / weekday mismatch")
 		return false
 	}
 	log.Println("[DEBUG] All fields match")
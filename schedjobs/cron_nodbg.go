@@ -6,18 +6,29 @@ import (
 	"time"
 )
 
+// Matches reports whether the job is due at the second of now, on the wall clock of its Location if set.
+// The second pass of a repeated hour never matches, so that the job runs once. An interval job never matches.
 func (job *CronJob) Matches(now time.Time) bool {
-	if (job.Minutes & (1 << now.Minute())) == 0 {
+	if job.Location != nil {
+		now = now.In(job.Location)
+	}
+	if job.Every > 0 {
 		return false
 	}
-	if (job.Hours & (1 << now.Hour())) == 0 {
+	if _, repeated := repeatedWallClock(now); repeated {
+		return false
+	}
+	if !job.secondMatches(now) {
 		return false
 	}
-	if (job.DaysOfMonth & (1 << (now.Day() - 1))) == 0 { // now.Day() = 1..31 -> bit 0 = day 1
+	if (job.Minutes & (1 << now.Minute())) == 0 {
+		return false
+	}
+	if (job.Hours & (1 << now.Hour())) == 0 {
 		return false
 	}
-	if (job.Weekdays & (1 << now.Weekday())) == 0 {
+	if !job.monthMatches(now) {
 		return false
 	}
-	return true
+	return job.dayMatches(now)
 }
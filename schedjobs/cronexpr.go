@@ -0,0 +1,258 @@
+package schedjobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+var weekdayNames = map[string]int{
+	"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+}
+
+// cronSearchLimit bounds NextAfter for specs that never match, e.g. "0 0 30 2 *"
+const cronSearchLimit = 5 // years
+
+// ParseCron parses a standard 5-field cron expression "minute hour day-of-month month weekday"
+// into a CronJob without ID and Task, e.g. "*/5 9-17 * * MON-FRI".
+// A 6-field expression starts with the second, e.g. "*/15 * * * * *" for every 15 seconds.
+// "@every <duration>" (at least 1s) makes an interval job, e.g. "@every 15s".
+// Fields take lists (1,15), ranges (9-17), steps (*/5, 0-30/10) and month/weekday names (JAN, MON).
+// Weekday 7 is Sunday, as is 0. Macros @yearly, @annually, @monthly, @weekly, @daily, @midnight and @hourly are supported.
+// When both day-of-month and weekday are restricted (neither starts with "*"), a day matching either one runs the job.
+// A leading "CRON_TZ=<IANA zone>" (or "TZ=") sets the Location, e.g. "CRON_TZ=Europe/Berlin 0 9 * * *".
+func ParseCron(expr string) (*CronJob, error) {
+	spec := strings.TrimSpace(expr)
+	var loc *time.Location
+	if rest, ok := cutTZPrefix(spec); ok {
+		zone, fields, _ := strings.Cut(rest, " ")
+		var err error
+		if loc, err = time.LoadLocation(zone); err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", expr, err)
+		}
+		spec = strings.TrimSpace(fields)
+	}
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || every < time.Second {
+			return nil, fmt.Errorf("cron expression %q: invalid interval %q", expr, rest)
+		}
+		return &CronJob{Spec: expr, Every: every}, nil
+	}
+	if strings.HasPrefix(spec, "@") {
+		expanded, ok := cronMacros[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported cron macro %q", spec)
+		}
+		spec = expanded
+	}
+	fields := strings.Fields(spec)
+	var seconds uint64
+	switch len(fields) {
+	case 5:
+	case 6:
+		var err error
+		if seconds, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+			return nil, fmt.Errorf("cron second %q: %w", fields[0], err)
+		}
+		fields = fields[1:]
+	default:
+		return nil, fmt.Errorf("cron expression %q: expected 5 or 6 fields, got %d", expr, len(fields))
+	}
+	minutes, err := parseCronField(fields[0], 0, 59, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cron minute %q: %w", fields[0], err)
+	}
+	hours, err := parseCronField(fields[1], 0, 23, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cron hour %q: %w", fields[1], err)
+	}
+	days, err := parseCronField(fields[2], 1, 31, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cron day of month %q: %w", fields[2], err)
+	}
+	months, err := parseCronField(fields[3], 1, 12, monthNames)
+	if err != nil {
+		return nil, fmt.Errorf("cron month %q: %w", fields[3], err)
+	}
+	weekdays, err := parseCronField(fields[4], 0, 7, weekdayNames)
+	if err != nil {
+		return nil, fmt.Errorf("cron weekday %q: %w", fields[4], err)
+	}
+	if weekdays&(1<<7) != 0 { // 7 = Sunday
+		weekdays = weekdays&^(1<<7) | 1
+	}
+	return &CronJob{
+		Spec:         expr,
+		Seconds:      seconds,
+		Minutes:      minutes,
+		Hours:        uint32(hours),
+		DaysOfMonth:  uint32(days >> 1), // day 1 = bit 0
+		Months:       uint16(months >> 1),
+		Weekdays:     uint8(weekdays),
+		DayOrWeekday: !strings.HasPrefix(fields[2], "*") && !strings.HasPrefix(fields[4], "*"),
+		Location:     loc,
+	}, nil
+}
+
+func cutTZPrefix(spec string) (string, bool) {
+	if rest, ok := strings.CutPrefix(spec, "CRON_TZ="); ok {
+		return rest, true
+	}
+	return strings.CutPrefix(spec, "TZ=")
+}
+
+// parseCronField returns the bits of the values (bit n = value n) of a comma-separated field
+func parseCronField(field string, lo int, hi int, names map[string]int) (uint64, error) {
+	var set uint64
+	for part := range strings.SplitSeq(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+		var start, end int
+		switch {
+		case rangePart == "*":
+			start, end = lo, hi
+		case strings.Contains(rangePart, "-"):
+			startPart, endPart, _ := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = parseCronValue(startPart, lo, hi, names); err != nil {
+				return 0, err
+			}
+			if end, err = parseCronValue(endPart, lo, hi, names); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			var err error
+			if start, err = parseCronValue(rangePart, lo, hi, names); err != nil {
+				return 0, err
+			}
+			end = start
+			if hasStep { // "a/s" = from a to the max
+				end = hi
+			}
+		}
+		for v := start; v <= end; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func parseCronValue(s string, lo int, hi int, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToUpper(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < lo || v > hi {
+		return 0, fmt.Errorf("value %d out of range %d-%d", v, lo, hi)
+	}
+	return v, nil
+}
+
+// MustParseCron is ParseCron for static expressions. Panics on an invalid expression
+func MustParseCron(expr string) *CronJob {
+	job, err := ParseCron(expr)
+	if err != nil {
+		panic(err)
+	}
+	return job
+}
+
+// NextAfter returns the first second strictly after t matching the job, in its Location (or t's location if nil).
+// DST gaps are skipped and repeated hours run once, as Matches does.
+// An interval job returns the next multiple of Every since the zero time, so that all instances agree on the due times.
+// Returns the zero time if nothing matches within 5 years.
+func (job *CronJob) NextAfter(t time.Time) time.Time {
+	if job.Every > 0 {
+		return t.Truncate(job.Every).Add(job.Every)
+	}
+	loc := job.location(t.Location())
+	t = t.In(loc).Truncate(time.Second).Add(time.Second)
+	limit := t.AddDate(cronSearchLimit, 0, 0)
+	for t.Before(limit) {
+		if _, repeated := repeatedWallClock(t); repeated {
+			t = t.Truncate(time.Minute).Add(time.Minute) // the first pass was already considered
+			continue
+		}
+		y, m, d := t.Date()
+		switch {
+		case !job.monthMatches(t):
+			t = firstOccurrence(time.Date(y, m+1, 1, 0, 0, 0, 0, loc))
+		case !job.dayMatches(t):
+			t = firstOccurrence(time.Date(y, m, d+1, 0, 0, 0, 0, loc))
+		case job.Hours&(1<<t.Hour()) == 0:
+			t = firstOccurrence(time.Date(y, m, d, t.Hour()+1, 0, 0, 0, loc))
+		case job.Minutes&(1<<t.Minute()) == 0:
+			t = t.Truncate(time.Minute).Add(time.Minute)
+		case !job.secondMatches(t):
+			t = t.Add(time.Second)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// NextRuns returns up to n upcoming run times after t, e.g. for display
+func (job *CronJob) NextRuns(t time.Time, n int) []time.Time {
+	runs := make([]time.Time, 0, n)
+	for range n {
+		t = job.NextAfter(t)
+		if t.IsZero() {
+			break
+		}
+		runs = append(runs, t)
+	}
+	return runs
+}
+
+// secondMatches reports whether the second of t is in Seconds. Seconds = 0 matches second 0 only
+func (job *CronJob) secondMatches(t time.Time) bool {
+	if job.Seconds == 0 {
+		return t.Second() == 0
+	}
+	return job.Seconds&(1<<t.Second()) != 0
+}
+
+// monthMatches reports whether the month of t is in Months. Months = 0 matches every month
+func (job *CronJob) monthMatches(t time.Time) bool {
+	return job.Months == 0 || job.Months&(1<<(t.Month()-1)) != 0
+}
+
+// dayMatches applies DaysOfMonth and Weekdays to the date of t, ORed if DayOrWeekday
+func (job *CronJob) dayMatches(t time.Time) bool {
+	dom := job.DaysOfMonth&(1<<(t.Day()-1)) != 0 // t.Day() = 1..31 -> bit 0 = day 1
+	dow := job.Weekdays&(1<<t.Weekday()) != 0
+	if job.DayOrWeekday {
+		return dom || dow
+	}
+	return dom && dow
+}
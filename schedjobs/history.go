@@ -0,0 +1,76 @@
+package schedjobs
+
+import (
+	"time"
+)
+
+const (
+	DefaultHistorySize = 20   // runs kept per job
+	maxHistoryJobs     = 1000 // jobs with a history. the one that ran least recently is evicted beyond it
+)
+
+// RunRecord is a finished run of a job
+type RunRecord struct {
+	JobID    string    `json:"job_id"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Attempts int       `json:"attempts"`
+	Err      string    `json:"error,omitzero"` // error of the last attempt. "" = success
+}
+
+func (r *RunRecord) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// recordRun appends a run to the history of the job, keeping the last HistorySize runs
+func (s *Scheduler) recordRun(jobID string, start time.Time, attempts int, err error) {
+	rec := RunRecord{JobID: jobID, Start: start, End: time.Now(), Attempts: attempts}
+	if err != nil {
+		rec.Err = err.Error()
+	}
+	size := s.HistorySize
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	runs, exists := s.history[jobID]
+	if !exists && len(s.history) >= maxHistoryJobs {
+		s.evictHistory()
+	}
+	runs = append(runs, rec)
+	if len(runs) > size {
+		runs = append(runs[:0:0], runs[len(runs)-size:]...)
+	}
+	s.history[jobID] = runs
+}
+
+// evictHistory drops the history of the job that ran least recently. Call with s.mu held
+func (s *Scheduler) evictHistory() {
+	var oldestID string
+	var oldest time.Time
+	for id, runs := range s.history {
+		if end := runs[len(runs)-1].End; oldestID == "" || end.Before(oldest) {
+			oldestID, oldest = id, end
+		}
+	}
+	delete(s.history, oldestID)
+}
+
+// History returns a copy of the recent runs of the job, oldest first
+func (s *Scheduler) History(jobID string) []RunRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]RunRecord(nil), s.history[jobID]...)
+}
+
+// LastRun returns the latest run of the job
+func (s *Scheduler) LastRun(jobID string) (RunRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	runs := s.history[jobID]
+	if len(runs) == 0 {
+		return RunRecord{}, false
+	}
+	return runs[len(runs)-1], true
+}
@@ -0,0 +1,204 @@
+package schedjobs
+
+import (
+	"context"
+	"encoding/json/v2"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Catch-up Policies for persisted one-time jobs whose ExecTime passed while the process was down
+const (
+	CatchUpRun    = "run"    // run them right after start
+	CatchUpSkip   = "skip"   // drop them
+	CatchUpWithin = "within" // run them if late by no more than CatchUpWindow, else drop them
+)
+
+// StoreConf is loaded from config/.jobstore.json
+type StoreConf struct {
+	Backend       string `json:"backend"`         // "sql" or "kv"
+	DBName        string `json:"db_name"`         // [sql] key of the SQL database in .sql-databases.json
+	Table         string `json:"table"`           // [sql] Default: DefaultJobTable
+	KVKey         string `json:"kv_key"`          // [kv] hash key. Default: DefaultJobKVKey
+	CatchUp       string `json:"catch_up"`        // CatchUpXXX: run, skip or within. "" = run
+	CatchUpWindow int    `json:"catch_up_window"` // [within] seconds
+}
+
+// JobRecord is a persisted one-time job.
+// Timeout and the RetryPolicy are kept except RetryPolicy.Retryable, so a restored job retries on every error
+type JobRecord struct {
+	ID               string    `json:"id"`
+	Type             string    `json:"type"`    // registered task type
+	Payload          string    `json:"payload"` // JSON. passed to the task type's handler
+	ExecTime         time.Time `json:"exec_time"`
+	CreatedAt        time.Time `json:"created_at"`
+	TimeoutMS        int64     `json:"timeout_ms"`
+	RetryMaxAttempts int       `json:"retry_max_attempts"`
+	RetryBaseDelayMS int64     `json:"retry_base_delay_ms"`
+	RetryMaxDelayMS  int64     `json:"retry_max_delay_ms"`
+	RetryJitter      float64   `json:"retry_jitter"`
+}
+
+func (r *JobRecord) FieldsToScan() []any {
+	return []any{&r.ID, &r.Type, &r.Payload, &r.ExecTime, &r.CreatedAt,
+		&r.TimeoutMS, &r.RetryMaxAttempts, &r.RetryBaseDelayMS, &r.RetryMaxDelayMS, &r.RetryJitter}
+}
+
+func newJobRecord(job *OneTimeJob, now time.Time) *JobRecord {
+	return &JobRecord{
+		ID:               job.ID,
+		Type:             job.Type,
+		Payload:          job.Payload,
+		ExecTime:         job.ExecTime,
+		CreatedAt:        now,
+		TimeoutMS:        job.Timeout.Milliseconds(),
+		RetryMaxAttempts: job.Retry.MaxAttempts,
+		RetryBaseDelayMS: job.Retry.BaseDelay.Milliseconds(),
+		RetryMaxDelayMS:  job.Retry.MaxDelay.Milliseconds(),
+		RetryJitter:      job.Retry.Jitter,
+	}
+}
+
+// job returns the OneTimeJob of the record without its Task
+func (r *JobRecord) job() *OneTimeJob {
+	return &OneTimeJob{
+		ID:       r.ID,
+		Type:     r.Type,
+		Payload:  r.Payload,
+		ExecTime: r.ExecTime,
+		Timeout:  time.Duration(r.TimeoutMS) * time.Millisecond,
+		Retry: RetryPolicy{
+			MaxAttempts: r.RetryMaxAttempts,
+			BaseDelay:   time.Duration(r.RetryBaseDelayMS) * time.Millisecond,
+			MaxDelay:    time.Duration(r.RetryMaxDelayMS) * time.Millisecond,
+			Jitter:      r.RetryJitter,
+		},
+	}
+}
+
+// JobStore persists the one-time jobs with a Type, so that they survive restarts
+type JobStore interface {
+	SaveJob(ctx context.Context, rec *JobRecord) error // insert or replace by ID
+	DeleteJob(ctx context.Context, id string) error
+	LoadJobs(ctx context.Context) ([]*JobRecord, error)
+}
+
+// TaskHandler runs a persisted job of a task type with its payload
+type TaskHandler func(ctx context.Context, payload string) error
+
+// RegisterTaskType registers the handler of a task type. Register all the types before Start to restore their jobs
+func (s *Scheduler) RegisterTaskType(name string, handler TaskHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.taskTypes[name] = handler
+}
+
+// RegisterTypedTask registers a task type whose JSON payload is decoded into P
+func RegisterTypedTask[P any](s *Scheduler, name string, handler func(ctx context.Context, payload P) error) {
+	s.RegisterTaskType(name, func(ctx context.Context, payload string) error {
+		var p P
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return fmt.Errorf("task type %s: invalid payload: %w", name, err)
+		}
+		return handler(ctx, p)
+	})
+}
+
+// SetJobStore persists the one-time jobs with a Type and restores them on Start. Call before Start
+func (s *Scheduler) SetJobStore(store JobStore) {
+	s.store = store
+}
+
+func (s *Scheduler) taskHandler(name string) (TaskHandler, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	handler, ok := s.taskTypes[name]
+	return handler, ok
+}
+
+// bindTaskType sets the Task of a job with a Type from its registered handler
+func (s *Scheduler) bindTaskType(job *OneTimeJob) error {
+	handler, ok := s.taskHandler(job.Type)
+	if !ok {
+		return fmt.Errorf("job %s: task type %q not registered", job.ID, job.Type)
+	}
+	payload := job.Payload
+	job.Task = func(ctx context.Context) error {
+		return handler(ctx, payload)
+	}
+	return nil
+}
+
+// persistJob saves a job with a Type to the store if any
+func (s *Scheduler) persistJob(job *OneTimeJob) error {
+	if s.store == nil || job.Type == "" {
+		return nil
+	}
+	if job.ID == "" {
+		return fmt.Errorf("persisted job of type %q requires an ID", job.Type)
+	}
+	return s.store.SaveJob(s.Ctx, newJobRecord(job, time.Now()))
+}
+
+// forgetJob deletes a finished job from the store.
+// A job interrupted by the shutdown is kept to run again after the restart.
+func (s *Scheduler) forgetJob(job *OneTimeJob) {
+	if s.store == nil || job.Type == "" || s.jobsCtx.Err() != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(s.Ctx), storeTimeout)
+	defer cancel()
+	if err := s.store.DeleteJob(ctx, job.ID); err != nil {
+		log.Printf("[ERROR][Scheduler] failed to delete persisted job %s: %v", job.ID, err)
+	}
+}
+
+// restoreJobs loads the persisted jobs, scheduling the future ones and applying the CatchUp policy to the missed ones.
+// Returns the missed jobs to run right after start
+func (s *Scheduler) restoreJobs(now time.Time) []*OneTimeJob {
+	if s.store == nil {
+		return nil
+	}
+	recs, err := s.store.LoadJobs(s.Ctx)
+	if err != nil {
+		log.Printf("[ERROR][Scheduler] failed to load persisted jobs: %v", err)
+		return nil
+	}
+	var missed []*OneTimeJob
+	restored := 0
+	for _, rec := range recs {
+		job := rec.job()
+		if err = s.bindTaskType(job); err != nil {
+			log.Printf("[ERROR][Scheduler] persisted job not restored: %v", err) // kept for a later version
+			continue
+		}
+		if rec.ExecTime.After(now) {
+			s.scheduleOneTimeJob(job)
+			restored++
+			continue
+		}
+		if s.catchUp(now.Sub(rec.ExecTime)) {
+			missed = append(missed, job)
+			continue
+		}
+		log.Printf("[WARN][Scheduler] persisted job %s missed at %v. dropped by the catch-up policy %q", job.ID, rec.ExecTime, s.CatchUp)
+		if err = s.store.DeleteJob(s.Ctx, job.ID); err != nil {
+			log.Printf("[ERROR][Scheduler] failed to delete persisted job %s: %v", job.ID, err)
+		}
+	}
+	log.Printf("[INFO][Scheduler] %d persisted jobs restored, %d to run now", restored, len(missed))
+	return missed
+}
+
+// catchUp reports whether a job missed by late should run by the CatchUp policy
+func (s *Scheduler) catchUp(late time.Duration) bool {
+	switch s.CatchUp {
+	case CatchUpSkip:
+		return false
+	case CatchUpWithin:
+		return late <= s.CatchUpWindow
+	default:
+		return true
+	}
+}
@@ -0,0 +1,59 @@
+package schedjobs
+
+import (
+	"context"
+	"encoding/json/v2"
+	"log"
+	"sort"
+
+	"github.com/logitools/gw/db/kvdb"
+)
+
+const DefaultJobKVKey = "schedjobs:onetime"
+
+// KVJobStore keeps the persisted jobs in a KV hash: job id -> JSON JobRecord
+type KVJobStore struct {
+	client kvdb.Client
+	key    string
+}
+
+// NewKVJobStore creates a KVJobStore on the hash key. key defaults to DefaultJobKVKey
+func NewKVJobStore(client kvdb.Client, key string) *KVJobStore {
+	if key == "" {
+		key = DefaultJobKVKey
+	}
+	return &KVJobStore{client: client, key: key}
+}
+
+func (s *KVJobStore) SaveJob(ctx context.Context, rec *JobRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.client.SetField(ctx, s.key, rec.ID, string(data))
+}
+
+func (s *KVJobStore) DeleteJob(ctx context.Context, id string) error {
+	_, err := s.client.RemoveFields(ctx, s.key, id)
+	return err
+}
+
+func (s *KVJobStore) LoadJobs(ctx context.Context) ([]*JobRecord, error) {
+	fields, err := s.client.GetAllFields(ctx, s.key)
+	if err != nil {
+		return nil, err
+	}
+	recs := make([]*JobRecord, 0, len(fields))
+	for id, data := range fields {
+		var rec JobRecord
+		if err = json.Unmarshal([]byte(data), &rec); err != nil {
+			log.Printf("[ERROR][Scheduler] invalid persisted job %s: %v", id, err)
+			continue
+		}
+		recs = append(recs, &rec)
+	}
+	sort.Slice(recs, func(i, j int) bool {
+		return recs[i].ExecTime.Before(recs[j].ExecTime)
+	})
+	return recs, nil
+}
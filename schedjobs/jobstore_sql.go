@@ -0,0 +1,94 @@
+package schedjobs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/logitools/gw/db/sqldb"
+)
+
+const DefaultJobTable = "scheduled_jobs"
+
+// jobColumns in the order of JobRecord.FieldsToScan
+var jobColumns = []string{"id", "type", "payload", "exec_time", "created_at",
+	"timeout_ms", "retry_max_attempts", "retry_base_delay_ms", "retry_max_delay_ms", "retry_jitter"}
+
+// jobUpdateColumns are replaced on saving an existing id. all but id and created_at
+var jobUpdateColumns = []string{"type", "payload", "exec_time",
+	"timeout_ms", "retry_max_attempts", "retry_base_delay_ms", "retry_max_delay_ms", "retry_jitter"}
+
+// SQLJobStore keeps the persisted jobs in a table.
+//
+// PostgreSQL:
+//
+//	CREATE TABLE scheduled_jobs (
+//	  id VARCHAR(255) PRIMARY KEY,
+//	  type VARCHAR(255) NOT NULL,
+//	  payload TEXT NOT NULL,
+//	  exec_time TIMESTAMPTZ NOT NULL,
+//	  created_at TIMESTAMPTZ NOT NULL,
+//	  timeout_ms BIGINT NOT NULL DEFAULT 0,
+//	  retry_max_attempts INTEGER NOT NULL DEFAULT 0,
+//	  retry_base_delay_ms BIGINT NOT NULL DEFAULT 0,
+//	  retry_max_delay_ms BIGINT NOT NULL DEFAULT 0,
+//	  retry_jitter DOUBLE PRECISION NOT NULL DEFAULT 0
+//	);
+//
+// MySQL:
+//
+//	CREATE TABLE scheduled_jobs (
+//	  id VARCHAR(255) NOT NULL PRIMARY KEY,
+//	  type VARCHAR(255) NOT NULL,
+//	  payload TEXT NOT NULL,
+//	  exec_time DATETIME(6) NOT NULL,
+//	  created_at DATETIME(6) NOT NULL,
+//	  timeout_ms BIGINT NOT NULL DEFAULT 0,
+//	  retry_max_attempts INT NOT NULL DEFAULT 0,
+//	  retry_base_delay_ms BIGINT NOT NULL DEFAULT 0,
+//	  retry_max_delay_ms BIGINT NOT NULL DEFAULT 0,
+//	  retry_jitter DOUBLE NOT NULL DEFAULT 0
+//	);
+type SQLJobStore struct {
+	dbClient  sqldb.Client
+	table     string
+	deleteSQL string
+	loadSQL   string
+}
+
+// NewSQLJobStore creates a SQLJobStore on the table. table defaults to DefaultJobTable
+func NewSQLJobStore(dbClient sqldb.Client, table string) (*SQLJobStore, error) {
+	if table == "" {
+		table = DefaultJobTable
+	}
+	if !sqldb.IdentifierRegexp.MatchString(table) {
+		return nil, fmt.Errorf("invalid job table: %q", table)
+	}
+	prefix := sqldb.PlaceholderPrefixForDBType[dbClient.Conf().Type]
+	return &SQLJobStore{
+		dbClient:  dbClient,
+		table:     table,
+		deleteSQL: sqldb.ReplaceStaticPlaceholders(fmt.Sprintf("DELETE FROM %s WHERE id = ?", table), prefix),
+		loadSQL:   fmt.Sprintf("SELECT %s FROM %s ORDER BY exec_time", strings.Join(jobColumns, ", "), table),
+	}, nil
+}
+
+func (s *SQLJobStore) SaveJob(ctx context.Context, rec *JobRecord) error {
+	_, err := sqldb.Upsert(ctx, s.dbClient, s.table,
+		jobColumns,
+		[]string{"id"},
+		jobUpdateColumns,
+		[][]any{{rec.ID, rec.Type, rec.Payload, rec.ExecTime, rec.CreatedAt,
+			rec.TimeoutMS, rec.RetryMaxAttempts, rec.RetryBaseDelayMS, rec.RetryMaxDelayMS, rec.RetryJitter}},
+	)
+	return err
+}
+
+func (s *SQLJobStore) DeleteJob(ctx context.Context, id string) error {
+	_, err := s.dbClient.Exec(ctx, s.deleteSQL, id)
+	return err
+}
+
+func (s *SQLJobStore) LoadJobs(ctx context.Context) ([]*JobRecord, error) {
+	return sqldb.RawQueryItems[JobRecord, *JobRecord](ctx, s.dbClient, s.loadSQL)
+}
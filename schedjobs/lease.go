@@ -0,0 +1,158 @@
+package schedjobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/logitools/gw/db/kvdb"
+)
+
+// Lease Modes for running the cron jobs once across the instances sharing a Lease.
+// Persisted one-time jobs take a lease per run in both modes. The other one-time jobs run where they were added.
+const (
+	LeasePerRun = "run"    // every cron run takes its own lease. any instance may run it
+	LeaseLeader = "leader" // only the instance holding the leader lease runs the cron jobs
+)
+
+const (
+	DefaultLeaseKeyPrefix = "schedjobs:lease:"
+	DefaultLeaderLeaseTTL = 30 * time.Second
+	// runLeaseTTL keeps a per-run lease past the wake-up of every instance, including a late one
+	runLeaseTTL      = 2 * time.Minute
+	leaseCallTimeout = time.Second
+	leaderLeaseName  = "leader"
+)
+
+// LeaseConf is loaded from config/.joblease.json
+type LeaseConf struct {
+	Mode      string `json:"mode"`       // LeaseXXX: run or leader
+	KeyPrefix string `json:"key_prefix"` // Default: DefaultLeaseKeyPrefix
+	Node      string `json:"node"`       // owner name of this instance. Default: "hostname:pid"
+	TTL       int    `json:"ttl"`        // [leader] seconds. 0 = DefaultLeaderLeaseTTL
+}
+
+// Lease is a distributed lease with fencing tokens.
+// A token increases on every new acquisition of the same fence, so that a resource can reject a stale holder.
+type Lease interface {
+	// Acquire takes the lease name for Owner, or extends it if already held by Owner.
+	// The token comes from the counter of fence. ok = false if held by another owner
+	Acquire(ctx context.Context, name string, fence string, ttl time.Duration) (token int64, ok bool, err error)
+	// Renew extends the lease if still held with the token
+	Renew(ctx context.Context, name string, token int64, ttl time.Duration) (bool, error)
+	// Release drops the lease if still held with the token
+	Release(ctx context.Context, name string, token int64) error
+	// Holder returns the current owner and token of the lease
+	Holder(ctx context.Context, name string) (owner string, token int64, found bool, err error)
+	Owner() string // this instance
+}
+
+// kvLeaseAcquireScript KEYS = lease key, fence key. ARGV = owner, ttl (ms). Returns {ok (1|0), token}
+const kvLeaseAcquireScript = `
+local owner = redis.call('HGET', KEYS[1], 'owner')
+if owner then
+  local token = tonumber(redis.call('HGET', KEYS[1], 'token'))
+  if owner ~= ARGV[1] then
+    return {0, token}
+  end
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+  return {1, token}
+end
+local token = redis.call('INCR', KEYS[2])
+redis.call('HSET', KEYS[1], 'owner', ARGV[1], 'token', token)
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return {1, token}
+`
+
+// kvLeaseRenewScript KEYS = lease key. ARGV = owner, token, ttl (ms). Returns 1 if renewed
+const kvLeaseRenewScript = `
+if redis.call('HGET', KEYS[1], 'owner') == ARGV[1] and redis.call('HGET', KEYS[1], 'token') == ARGV[2] then
+  redis.call('PEXPIRE', KEYS[1], ARGV[3])
+  return 1
+end
+return 0
+`
+
+// kvLeaseReleaseScript KEYS = lease key. ARGV = owner, token. Returns 1 if released
+const kvLeaseReleaseScript = `
+if redis.call('HGET', KEYS[1], 'owner') == ARGV[1] and redis.call('HGET', KEYS[1], 'token') == ARGV[2] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`
+
+// KVLease implements Lease on the KV DB
+type KVLease struct {
+	client    kvdb.Client
+	keyPrefix string
+	owner     string
+}
+
+// NewKVLease creates a KVLease for this instance as owner. Defaults: keyPrefix DefaultLeaseKeyPrefix, owner "hostname:pid"
+func NewKVLease(client kvdb.Client, keyPrefix string, owner string) *KVLease {
+	if keyPrefix == "" {
+		keyPrefix = DefaultLeaseKeyPrefix
+	}
+	if owner == "" {
+		owner = defaultLeaseOwner()
+	}
+	return &KVLease{client: client, keyPrefix: keyPrefix, owner: owner}
+}
+
+func defaultLeaseOwner() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return hostname + ":" + strconv.Itoa(os.Getpid())
+}
+
+func (l *KVLease) Owner() string {
+	return l.owner
+}
+
+func (l *KVLease) Acquire(ctx context.Context, name string, fence string, ttl time.Duration) (int64, bool, error) {
+	keys := []string{l.keyPrefix + name, l.keyPrefix + "fence:" + fence}
+	res, err := l.client.Eval(ctx, kvLeaseAcquireScript, keys, l.owner, ttl.Milliseconds())
+	if err != nil {
+		return 0, false, err
+	}
+	reply, ok := res.([]any)
+	if !ok || len(reply) != 2 {
+		return 0, false, fmt.Errorf("unexpected script reply: %v", res)
+	}
+	acquired, ok1 := reply[0].(int64)
+	token, ok2 := reply[1].(int64)
+	if !ok1 || !ok2 {
+		return 0, false, fmt.Errorf("unexpected script reply: %v", res)
+	}
+	return token, acquired == 1, nil
+}
+
+func (l *KVLease) Renew(ctx context.Context, name string, token int64, ttl time.Duration) (bool, error) {
+	res, err := l.client.Eval(ctx, kvLeaseRenewScript, []string{l.keyPrefix + name}, l.owner, token, ttl.Milliseconds())
+	if err != nil {
+		return false, err
+	}
+	renewed, _ := res.(int64)
+	return renewed == 1, nil
+}
+
+func (l *KVLease) Release(ctx context.Context, name string, token int64) error {
+	_, err := l.client.Eval(ctx, kvLeaseReleaseScript, []string{l.keyPrefix + name}, l.owner, token)
+	return err
+}
+
+func (l *KVLease) Holder(ctx context.Context, name string) (string, int64, bool, error) {
+	fields, err := l.client.GetFields(ctx, l.keyPrefix+name, "owner", "token")
+	if err != nil || len(fields) < 2 {
+		return "", 0, false, err
+	}
+	token, err := strconv.ParseInt(fields["token"], 10, 64)
+	if err != nil {
+		return "", 0, false, err
+	}
+	return fields["owner"], token, true, nil
+}
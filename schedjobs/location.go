@@ -0,0 +1,46 @@
+package schedjobs
+
+import "time"
+
+// DST Policies of cron jobs, evaluated on the wall clock of the job's Location:
+//   - Gap (spring forward, e.g. 02:00-03:00 does not exist): the missing minutes are skipped.
+//     A job due only in the gap does not run that day. Schedule daily jobs outside 01:00-03:00 to avoid it.
+//   - Repeated hour (fall back, e.g. 02:00-03:00 occurs twice): a job runs once, in the first pass.
+
+// maxDSTShift bounds the search for a repeated wall clock. No zone shifts more than 2 hours at once
+const maxDSTShift = 2 * time.Hour
+
+// repeatedWallClock reports whether the wall clock of t already occurred earlier,
+// i.e. t is in the second pass of a repeated hour after a fall-back transition.
+// Returns the shift back to the first occurrence.
+func repeatedWallClock(t time.Time) (time.Duration, bool) {
+	_, offset := t.Zone()
+	_, offsetBefore := t.Add(-maxDSTShift).Zone()
+	if offsetBefore <= offset {
+		return 0, false
+	}
+	shift := time.Duration(offsetBefore-offset) * time.Second
+	if _, o := t.Add(-shift).Zone(); o != offsetBefore {
+		return 0, false // the transition is later than t - shift: no earlier occurrence
+	}
+	return shift, true
+}
+
+// firstOccurrence moves t to the first pass of a repeated wall clock. time.Date picks the second one
+func firstOccurrence(t time.Time) time.Time {
+	if shift, ok := repeatedWallClock(t); ok {
+		return t.Add(-shift)
+	}
+	return t
+}
+
+// location returns the zone of the job: its own Location, or else def, or else time.Local
+func (job *CronJob) location(def *time.Location) *time.Location {
+	if job.Location != nil {
+		return job.Location
+	}
+	if def != nil {
+		return def
+	}
+	return time.Local
+}
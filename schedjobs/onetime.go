@@ -1,12 +1,20 @@
 package schedjobs
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type OneTimeJob struct {
 	ID       string
 	ExecTime time.Time
-	Task     func() error
+	Task     func(ctx context.Context) error // ctx is canceled on Timeout or after the scheduler's ShutdownGrace. nil for a Type
+	Timeout  time.Duration                   // 0 = no timeout
+	Retry    RetryPolicy                     // zero = no retry
+	Type     string                          // registered task type. set to persist the job in the scheduler's JobStore
+	Payload  string                          // [Type] JSON passed to the task type's handler
 	// Job-specific callbacks
 	OnAdded    func()
-	OnFinished func(error)
+	OnFinished func(err error, attempts int)
+	OnRetry    func(attempt int, err error, delay time.Duration) // before waiting for the next attempt
 }
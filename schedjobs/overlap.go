@@ -0,0 +1,81 @@
+package schedjobs
+
+import (
+	"context"
+	"log"
+)
+
+// Overlap Policies of a cron job due while its previous run is still running
+const (
+	OverlapAllow    = iota // start another run concurrently
+	OverlapSkip            // skip this run
+	OverlapQueueOne        // run once more right after the running one finishes. further runs are skipped
+	OverlapReplace         // cancel the ctx of the running one and start a new run
+)
+
+// cronRuns tracks the running runs of a cron job
+type cronRuns struct {
+	active int                // runs in progress
+	ctx    context.Context    // of the current runs
+	cancel context.CancelFunc // cancels the current runs
+	queued bool               // [OverlapQueueOne] a run waits for the active one
+	token  int64              // [OverlapQueueOne] fencing token of the queued run
+}
+
+// startCronRun starts a run of the job in a goroutine, applying its Overlap policy.
+// token is the fencing token of the run's lease. 0 = none
+func (s *Scheduler) startCronRun(job *CronJob, token int64) {
+	s.mu.Lock()
+	runs, running := s.running[job.ID]
+	if running && runs.active > 0 {
+		switch job.Overlap {
+		case OverlapSkip:
+			s.mu.Unlock()
+			log.Printf("[INFO][Scheduler] cron job %s still running. skipped", job.ID)
+			return
+		case OverlapQueueOne:
+			runs.queued = true
+			runs.token = token
+			s.mu.Unlock()
+			return
+		case OverlapReplace:
+			runs.cancel()
+			runs.ctx, runs.cancel = context.WithCancel(s.jobsCtx)
+		}
+	}
+	if !running {
+		runs = &cronRuns{}
+		runs.ctx, runs.cancel = context.WithCancel(s.jobsCtx)
+		s.running[job.ID] = runs
+	}
+	runs.active++
+	ctx := runs.ctx
+	s.wg.Add(1)
+	s.mu.Unlock()
+	go func() {
+		defer s.wg.Done()
+		s.execCronJob(ctx, job, token)
+		s.finishCronRun(job)
+	}()
+}
+
+// finishCronRun releases the run, starting the queued one if any
+func (s *Scheduler) finishCronRun(job *CronJob) {
+	s.mu.Lock()
+	runs := s.running[job.ID]
+	runs.active--
+	if runs.active > 0 {
+		s.mu.Unlock()
+		return
+	}
+	if runs.queued && s.Ctx.Err() == nil {
+		runs.queued = false
+		token := runs.token
+		s.mu.Unlock()
+		s.startCronRun(job, token)
+		return
+	}
+	runs.cancel()
+	delete(s.running, job.ID)
+	s.mu.Unlock()
+}
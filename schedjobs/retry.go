@@ -0,0 +1,110 @@
+package schedjobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"runtime/debug"
+	"time"
+)
+
+const DefaultRetryBaseDelay = time.Second
+
+// RetryPolicy retries a failed task within the same run. The zero value runs a task once
+type RetryPolicy struct {
+	MaxAttempts int              // including the first. <= 1 = no retry
+	BaseDelay   time.Duration    // before the 2nd attempt, doubled for each next one. 0 = DefaultRetryBaseDelay
+	MaxDelay    time.Duration    // cap of the delay. 0 = no cap
+	Jitter      float64          // 0..1. the delay is shortened by up to this fraction at random
+	Retryable   func(error) bool // nil = every error is retryable
+}
+
+// delay returns the backoff before the attempt following the failed one
+func (p *RetryPolicy) delay(failedAttempt int) time.Duration {
+	base := p.BaseDelay
+	if base <= 0 {
+		base = DefaultRetryBaseDelay
+	}
+	d := base << min(failedAttempt-1, 30)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) { // d <= 0 on overflow
+		d = p.MaxDelay
+	}
+	if jitter := min(max(p.Jitter, 0), 1); jitter > 0 {
+		d -= time.Duration(rand.Float64() * jitter * float64(d))
+	}
+	return d
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+type attemptKey struct{}
+
+// AttemptFromContext returns the attempt number (1 = first) of the running task
+func AttemptFromContext(ctx context.Context) int {
+	if attempt, ok := ctx.Value(attemptKey{}).(int); ok {
+		return attempt
+	}
+	return 1
+}
+
+// taskRun is a run of a job's task with its attempts
+type taskRun struct {
+	jobID   string
+	task    func(ctx context.Context) error
+	timeout time.Duration
+	retry   RetryPolicy
+	onRetry func(attempt int, err error, delay time.Duration) // job-specific callback
+	token   int64                                             // fencing token of the lease. 0 = none
+}
+
+// runTask runs the task, retrying on failure by the retry policy until ctx is done, and records the run in the history.
+// Returns the number of attempts and the error of the last one
+func (s *Scheduler) runTask(ctx context.Context, run taskRun) (attempts int, err error) {
+	start := time.Now()
+	defer func() {
+		s.recordRun(run.jobID, start, attempts, err)
+	}()
+	for attempt := 1; ; attempt++ {
+		err = s.runAttempt(ctx, run, attempt)
+		if err == nil || attempt >= run.retry.MaxAttempts || !run.retry.retryable(err) {
+			return attempt, err
+		}
+		delay := run.retry.delay(attempt)
+		if run.onRetry != nil {
+			run.onRetry(attempt, err, delay)
+		}
+		if s.OnJobRetry != nil {
+			s.OnJobRetry(run.jobID, attempt, err, delay)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempt, err
+		case <-timer.C:
+		}
+	}
+}
+
+// runAttempt runs the task once with its timeout, converting a panic into an error
+func (s *Scheduler) runAttempt(ctx context.Context, run taskRun, attempt int) (err error) {
+	ctx = context.WithValue(ctx, attemptKey{}, attempt)
+	if run.token > 0 {
+		ctx = context.WithValue(ctx, fencingTokenKey{}, run.token)
+	}
+	if run.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, run.timeout)
+		defer cancel()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[PANIC][Scheduler] job %s panicked: %v\n%s", run.jobID, r, debug.Stack())
+			err = fmt.Errorf("job %s panicked: %v", run.jobID, r)
+		}
+	}()
+	return run.task(ctx)
+}
@@ -6,25 +6,56 @@ import (
 	"log"
 	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/logitools/gw/svc"
 )
 
+// DefaultShutdownGrace is how long running jobs may finish on shutdown before their contexts are canceled
+const DefaultShutdownGrace = 10 * time.Second
+
+// shutdownCancelWait is how long to wait for the jobs after their contexts are canceled
+const shutdownCancelWait = 5 * time.Second
+
+// storeTimeout bounds a JobStore call made outside the scheduler's context
+const storeTimeout = 10 * time.Second
+
 type Scheduler struct {
 	Ctx         context.Context    // Service Context
 	cancel      context.CancelFunc // Service Context CancelFunc
-	state       int                // internal service state
-	done        chan error         // Shutdown Error Channel
-	oneTimeJobs map[int64][]*OneTimeJob
+	jobsCtx     context.Context    // parent of the task contexts. outlives Ctx by ShutdownGrace
+	jobsCancel  context.CancelFunc
+	state       int                     // internal service state
+	done        chan error              // Shutdown Error Channel
+	oneTimeJobs map[int64][]*OneTimeJob // unix second of ExecTime -> jobs
 	cronJobs    map[string]*CronJob
+	running     map[string]*cronRuns   // cron job id -> runs in progress. guarded by mu
+	timers      timerHeap              // due times of the jobs. guarded by mu
+	wake        chan struct{}          // re-arms the run loop after a timer is pushed
+	paused      map[string]bool        // paused cron job ids. guarded by mu
+	history     map[string][]RunRecord // job id -> recent runs. guarded by mu
+	taskTypes   map[string]TaskHandler // RegisterTaskType. guarded by mu
+	store       JobStore               // SetJobStore. nil = one-time jobs in memory only
+	lease       Lease                  // SetLease. nil = every instance runs every job
+	leaseMode   string                 // LeaseXXX
+	leaderToken atomic.Int64           // [LeaseLeader] fencing token while this instance leads. 0 = not the leader
 	mu          sync.Mutex
 	wg          sync.WaitGroup
+	Location    *time.Location // wall clock zone of the cron jobs without their own Location. nil = time.Local
+	// ShutdownGrace lets running jobs finish on shutdown before their contexts are canceled. 0 = cancel at once
+	ShutdownGrace time.Duration
+	CatchUp       string        // CatchUpXXX for persisted jobs missed while down. "" = CatchUpRun
+	CatchUpWindow time.Duration // [CatchUpWithin]
+	HistorySize   int           // runs kept per job. 0 = DefaultHistorySize
+	// LeaderLeaseTTL of the leader lease, renewed every third of it. 0 = DefaultLeaderLeaseTTL
+	LeaderLeaseTTL time.Duration
 	// Default Callbacks
 	OnOneTimeJobAdded    func(job *OneTimeJob)
 	OnCronJobAdded       func(job *CronJob)
-	OnOneTimeJobFinished func(job *OneTimeJob, err error)
-	OnCronJobFinished    func(job *CronJob, err error)
+	OnOneTimeJobFinished func(job *OneTimeJob, err error, attempts int)
+	OnCronJobFinished    func(job *CronJob, err error, attempts int)
+	OnJobRetry           func(jobID string, attempt int, err error, delay time.Duration)
 	OnOneTimeJobDeleted  func(job *OneTimeJob)
 	OnCronJobDeleted     func(job *CronJob)
 }
@@ -35,13 +66,22 @@ func (s *Scheduler) Name() string {
 
 func NewScheduler(parentCtx context.Context) *Scheduler {
 	svcCtx, svcCancel := context.WithCancel(parentCtx)
+	jobsCtx, jobsCancel := context.WithCancel(context.WithoutCancel(svcCtx))
 	return &Scheduler{
-		Ctx:         svcCtx,
-		cancel:      svcCancel,
-		state:       svc.StateREADY,
-		done:        make(chan error, 1),
-		oneTimeJobs: make(map[int64][]*OneTimeJob),
-		cronJobs:    make(map[string]*CronJob),
+		Ctx:           svcCtx,
+		cancel:        svcCancel,
+		jobsCtx:       jobsCtx,
+		jobsCancel:    jobsCancel,
+		state:         svc.StateREADY,
+		done:          make(chan error, 1),
+		oneTimeJobs:   make(map[int64][]*OneTimeJob),
+		cronJobs:      make(map[string]*CronJob),
+		running:       make(map[string]*cronRuns),
+		wake:          make(chan struct{}, 1),
+		paused:        make(map[string]bool),
+		history:       make(map[string][]RunRecord),
+		taskTypes:     make(map[string]TaskHandler),
+		ShutdownGrace: DefaultShutdownGrace,
 	}
 }
 
@@ -53,20 +93,23 @@ func (s *Scheduler) UseDefaultLoggers() {
 	s.OnCronJobAdded = func(job *CronJob) {
 		log.Printf("[INFO] cron job added: %s", job.ID)
 	}
-	s.OnCronJobFinished = func(job *CronJob, err error) {
+	s.OnCronJobFinished = func(job *CronJob, err error, attempts int) {
 		if err == nil {
-			log.Printf("[INFO] cron job finished: %s", job.ID)
+			log.Printf("[INFO] cron job finished: %s (attempts: %d)", job.ID, attempts)
 		} else {
-			log.Printf("[INFO] cron job finished: %s with error: %v", job.ID, err)
+			log.Printf("[INFO] cron job finished: %s with error: %v (attempts: %d)", job.ID, err, attempts)
 		}
 	}
-	s.OnOneTimeJobFinished = func(job *OneTimeJob, err error) {
+	s.OnOneTimeJobFinished = func(job *OneTimeJob, err error, attempts int) {
 		if err == nil {
-			log.Printf("[INFO] one-time job finished: %s", job.ID)
+			log.Printf("[INFO] one-time job finished: %s (attempts: %d)", job.ID, attempts)
 		} else {
-			log.Printf("[INFO] one-time job finished: %s with error: %v", job.ID, err)
+			log.Printf("[INFO] one-time job finished: %s with error: %v (attempts: %d)", job.ID, err, attempts)
 		}
 	}
+	s.OnJobRetry = func(jobID string, attempt int, err error, delay time.Duration) {
+		log.Printf("[WARN] job %s attempt %d failed: %v. retrying in %v", jobID, attempt, err, delay)
+	}
 }
 
 func (s *Scheduler) Start() error {
@@ -76,8 +119,15 @@ func (s *Scheduler) Start() error {
 	if s.state != svc.StateREADY {
 		return fmt.Errorf("cannot start. not ready")
 	}
+	missed := s.restoreJobs(time.Now())
 	s.state = svc.StateRUNNING
 	log.Println("[INFO][JobScheduler] service started")
+	if s.lease != nil && s.leaseMode == LeaseLeader {
+		go s.runLeaderElection()
+	}
+	for _, job := range missed {
+		s.runOneTimeJob(job)
+	}
 	go s.run()
 	return nil
 }
@@ -96,31 +146,71 @@ func (s *Scheduler) Done() <-chan error {
 	return s.done
 }
 
+// run sleeps until the earliest due time, waking early when a job is added
 func (s *Scheduler) run() {
-	ticker := time.NewTicker(time.Minute)
-	defer ticker.Stop()
+	wait := time.NewTimer(s.nextWait(time.Now()))
+	defer wait.Stop()
 	for {
 		select {
 		case <-s.Ctx.Done():
 			log.Println("[INFO][Scheduler] shutting down...")
-			s.wg.Wait()   // wait for all worker goroutines
+			s.waitJobs()
+			s.resignLeader()
 			s.done <- nil // clean shutdown
 			return
-		case now := <-ticker.C:
-			func() {
-				defer func() {
-					if r := recover(); r != nil {
-						log.Printf("[PANIC][Scheduler] panic recovered: %v\n%s", r, debug.Stack())
-					}
-				}()
-				s.runOneTimeJobs(now)
-				s.runCronJobs(now)
-			}()
+		case <-s.wake:
+		case <-wait.C:
+			s.runDue(time.Now())
+		}
+		wait.Reset(s.nextWait(time.Now()))
+	}
+}
+
+// runDue runs the jobs due at now. A timer firing early by the wall clock runs nothing and is re-armed
+func (s *Scheduler) runDue(now time.Time) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[PANIC][Scheduler] panic recovered: %v\n%s", r, debug.Stack())
+		}
+	}()
+	for _, t := range s.popDue(now) {
+		if t.cron != nil {
+			if s.IsPaused(t.cron.ID) {
+				continue
+			}
+			s.runCronJob(t.cron, t.at)
+		} else {
+			s.runOneTimeJobs(t.slot)
 		}
 	}
 }
 
-// GetOneTimeJobs returns a copy of all pending one-time jobs, keyed by their scheduled minute-level timestamp.
+// waitJobs waits for the running jobs for ShutdownGrace, then cancels their contexts and waits a little more.
+// Jobs ignoring the cancellation are abandoned.
+func (s *Scheduler) waitJobs() {
+	finished := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(finished)
+	}()
+	grace := time.NewTimer(s.ShutdownGrace)
+	defer grace.Stop()
+	select {
+	case <-finished:
+		s.jobsCancel()
+		return
+	case <-grace.C:
+	}
+	log.Printf("[WARN][Scheduler] jobs still running after %v. canceling their contexts", s.ShutdownGrace)
+	s.jobsCancel()
+	select {
+	case <-finished:
+	case <-time.After(shutdownCancelWait):
+		log.Printf("[ERROR][Scheduler] jobs ignored the cancellation for %v. abandoned", shutdownCancelWait)
+	}
+}
+
+// GetOneTimeJobs returns a copy of all pending one-time jobs, keyed by the unix second of their ExecTime.
 func (s *Scheduler) GetOneTimeJobs() map[int64][]*OneTimeJob {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -144,27 +234,22 @@ func (s *Scheduler) GetCronJobs() map[string]*CronJob {
 	return result
 }
 
+// AddOneTimeJob schedules a job to run once at its ExecTime (rounded up to the second).
+// A job with a Type runs its registered task type, and is persisted if the scheduler has a JobStore.
 func (s *Scheduler) AddOneTimeJob(job *OneTimeJob) error {
 	now := time.Now()
-	margin := 30 * time.Second
-	if job.ExecTime.Before(now.Add(margin)) {
-		return fmt.Errorf(
-			"cannot schedule job %s too close or in the past (ExecTime: %s, now: %s)",
-			job.ID, job.ExecTime, now,
-		)
-	}
-	// Round up to the next minute if ExecTime has seconds/nanoseconds
-	regTime := job.ExecTime
-	if regTime.Second() > 0 || regTime.Nanosecond() > 0 {
-		regTime = regTime.Truncate(time.Minute).Add(time.Minute)
-	}
-	key := regTime.Unix() / 60
-	s.mu.Lock()
-	if s.oneTimeJobs == nil {
-		s.oneTimeJobs = make(map[int64][]*OneTimeJob) // safety net
+	if !job.ExecTime.After(now) {
+		return fmt.Errorf("cannot schedule job %s in the past (ExecTime: %s, now: %s)", job.ID, job.ExecTime, now)
 	}
-	s.oneTimeJobs[key] = append(s.oneTimeJobs[key], job) // to make this safer?
-	s.mu.Unlock()
+	if job.Type != "" {
+		if err := s.bindTaskType(job); err != nil {
+			return err
+		}
+		if err := s.persistJob(job); err != nil {
+			return err
+		}
+	}
+	s.scheduleOneTimeJob(job)
 	if job.OnAdded != nil { // Job-specific callback
 		func() {
 			defer func() {
@@ -181,15 +266,34 @@ func (s *Scheduler) AddOneTimeJob(job *OneTimeJob) error {
 	return nil
 }
 
+// scheduleOneTimeJob puts the job in the slot of its ExecTime second
+func (s *Scheduler) scheduleOneTimeJob(job *OneTimeJob) {
+	key := job.ExecTime.Unix()
+	if job.ExecTime.Nanosecond() > 0 {
+		key++ // round up to the next second
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.oneTimeJobs == nil {
+		s.oneTimeJobs = make(map[int64][]*OneTimeJob) // safety net
+	}
+	if _, exists := s.oneTimeJobs[key]; !exists {
+		s.pushTimer(&timer{at: time.Unix(key, 0), slot: key})
+	}
+	s.oneTimeJobs[key] = append(s.oneTimeJobs[key], job)
+}
+
 func (s *Scheduler) AddCronJob(job *CronJob) error {
 	s.mu.Lock()
 	if s.cronJobs == nil {
 		s.cronJobs = make(map[string]*CronJob)
 	}
 	if _, exists := s.cronJobs[job.ID]; exists {
+		s.mu.Unlock()
 		return fmt.Errorf("cron job with ID %q already exists", job.ID)
 	}
 	s.cronJobs[job.ID] = job
+	s.scheduleCronJob(job, time.Now())
 	s.mu.Unlock()
 	// Job-specific callback
 	if job.OnAdded != nil {
@@ -209,14 +313,16 @@ func (s *Scheduler) AddCronJob(job *CronJob) error {
 	return nil
 }
 
-// DeleteOneTimeJob - Delete a job
-func (s *Scheduler) DeleteOneTimeJob(jobID string) {
+// DeleteOneTimeJob - Delete the pending jobs of the ID, also from the JobStore if persisted. Returns false if none
+func (s *Scheduler) DeleteOneTimeJob(jobID string) bool {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	found, persisted := false, false
 	for key, jobs := range s.oneTimeJobs {
 		filtered := jobs[:0]
 		for _, job := range jobs {
 			if job.ID == jobID {
+				found = true
+				persisted = persisted || job.Type != ""
 				if s.OnOneTimeJobDeleted != nil {
 					s.OnOneTimeJobDeleted(job)
 				}
@@ -230,9 +336,20 @@ func (s *Scheduler) DeleteOneTimeJob(jobID string) {
 			s.oneTimeJobs[key] = filtered
 		}
 	}
+	s.mu.Unlock()
+
+	// Not under the lock: the store may be slow. Not canceled by the shutdown, so that the job does not come back
+	if persisted && s.store != nil {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(s.Ctx), storeTimeout)
+		defer cancel()
+		if err := s.store.DeleteJob(ctx, jobID); err != nil {
+			log.Printf("[ERROR][Scheduler] failed to delete persisted job %s: %v", jobID, err)
+		}
+	}
+	return found
 }
 
-// DeleteCronJob removes a cron job by its ID
+// DeleteCronJob removes a cron job by its ID. Its timer is dropped when due
 func (s *Scheduler) DeleteCronJob(jobID string) {
 	s.mu.Lock()
 	job, exists := s.cronJobs[jobID]
@@ -241,9 +358,75 @@ func (s *Scheduler) DeleteCronJob(jobID string) {
 		return
 	}
 	delete(s.cronJobs, jobID)
+	delete(s.paused, jobID)
 	s.mu.Unlock()
 	// trigger global delete callback outside lock
 	if s.OnCronJobDeleted != nil {
 		s.OnCronJobDeleted(job)
 	}
 }
+
+// PauseCronJob stops the scheduled runs of a cron job until ResumeCronJob. Running ones are not affected
+func (s *Scheduler) PauseCronJob(jobID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.cronJobs[jobID]; !exists {
+		return fmt.Errorf("cron job %q not found", jobID)
+	}
+	s.paused[jobID] = true
+	return nil
+}
+
+// ResumeCronJob restarts the scheduled runs of a paused cron job from its next due time
+func (s *Scheduler) ResumeCronJob(jobID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.cronJobs[jobID]; !exists {
+		return fmt.Errorf("cron job %q not found", jobID)
+	}
+	delete(s.paused, jobID)
+	return nil
+}
+
+func (s *Scheduler) IsPaused(jobID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused[jobID]
+}
+
+// TriggerJob runs a job now. A cron job runs on this instance without a lease, by its Overlap policy, even if paused.
+// Pending one-time jobs of the ID run now instead of at their ExecTime
+func (s *Scheduler) TriggerJob(jobID string) error {
+	s.mu.Lock()
+	cronJob, isCron := s.cronJobs[jobID]
+	var oneTimeJobs []*OneTimeJob
+	if !isCron {
+		for key, jobs := range s.oneTimeJobs {
+			filtered := jobs[:0]
+			for _, job := range jobs {
+				if job.ID == jobID {
+					oneTimeJobs = append(oneTimeJobs, job)
+				} else {
+					filtered = append(filtered, job)
+				}
+			}
+			if len(filtered) == 0 {
+				delete(s.oneTimeJobs, key)
+			} else {
+				s.oneTimeJobs[key] = filtered
+			}
+		}
+	}
+	s.mu.Unlock()
+	if isCron {
+		s.startCronRun(cronJob, 0)
+		return nil
+	}
+	if len(oneTimeJobs) == 0 {
+		return fmt.Errorf("job %q not found", jobID)
+	}
+	for _, job := range oneTimeJobs {
+		s.runOneTimeJob(job)
+	}
+	return nil
+}
@@ -3,16 +3,17 @@
 package schedjobs
 
 import (
+	"context"
 	"log"
 	"time"
 )
 
-func (s *Scheduler) runOneTimeJobs(now time.Time) {
-	key := now.Unix() / 60
+// runOneTimeJobs runs the jobs of a slot due now
+func (s *Scheduler) runOneTimeJobs(slot int64) {
 	s.mu.Lock()
-	jobs := s.oneTimeJobs[key]
-	delete(s.oneTimeJobs, key)
-	log.Println("[DEBUG] runOneTimeJobs(now) with key: ", key)
+	jobs := s.oneTimeJobs[slot]
+	delete(s.oneTimeJobs, slot)
+	log.Printf("[DEBUG] runOneTimeJobs(slot) with slot %d: %d jobs", slot, len(jobs))
 	s.mu.Unlock()
 	for _, job := range jobs {
 		s.runOneTimeJob(job)
@@ -24,7 +25,26 @@ func (s *Scheduler) runOneTimeJob(job *OneTimeJob) {
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		err := job.Task()
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("[PANIC][Scheduler] recovered in the callbacks of job %s: %v", job.ID, r)
+			}
+		}()
+		token, ok := s.claimOneTimeRun(job)
+		if !ok {
+			log.Printf("[INFO][Scheduler] one-time job %s run by another instance", job.ID)
+			return
+		}
+		attempts, err := s.runTask(s.jobsCtx, taskRun{
+			jobID:   job.ID,
+			task:    job.Task,
+			timeout: job.Timeout,
+			retry:   job.Retry,
+			onRetry: job.OnRetry,
+			token:   token,
+		})
+		s.forgetJob(job)
+		log.Printf("[DEBUG] one-time job %s done after %d attempts", job.ID, attempts)
 		if job.OnFinished != nil {
 			func() {
 				defer func() {
@@ -32,46 +52,53 @@ func (s *Scheduler) runOneTimeJob(job *OneTimeJob) {
 						log.Println("[PANIC] Recovered in job.OnFinished:", r)
 					}
 				}()
-				job.OnFinished(err)
+				job.OnFinished(err, attempts)
 			}()
 		}
 		if s.OnOneTimeJobFinished != nil {
-			s.OnOneTimeJobFinished(job, err)
+			s.OnOneTimeJobFinished(job, err, attempts)
 		}
 	}()
 }
 
-func (s *Scheduler) runCronJobs(now time.Time) {
-	log.Println("[DEBUG] runCronJobs called at", now)
-	s.mu.Lock()
-	log.Println("[DEBUG] total cron jobs:", len(s.cronJobs))
-	// Copy values to a slice so we can unlock early
-	jobs := make([]*CronJob, 0, len(s.cronJobs))
-	for _, job := range s.cronJobs {
-		jobs = append(jobs, job)
-	}
-	log.Printf("[DEBUG] %d cronjobs copied", len(jobs))
-	s.mu.Unlock()
-	for _, job := range jobs {
-		log.Println("[DEBUG] matching cron job spec for ", job.ID)
-		if job.Matches(now) {
-			log.Println("[DEBUG] cron job spec MATCHED for ", job.ID)
-			s.runCronJob(job)
-		}
+// runCronJob runs the job due at, once across the instances if the scheduler has a Lease
+func (s *Scheduler) runCronJob(job *CronJob, at time.Time) {
+	log.Printf("[DEBUG] runCronJob() called for %s due at %v. overlap policy: %d", job.ID, at, job.Overlap)
+	token, ok := s.claimCronRun(job, at)
+	if !ok {
+		log.Printf("[DEBUG] cron job %s due at %v not claimed", job.ID, at)
+		return
 	}
+	s.startCronRun(job, token)
 }
 
-func (s *Scheduler) runCronJob(job *CronJob) {
-	log.Println("[DEBUG] runCronJob() called")
-	s.wg.Add(1)
-	go func() {
-		defer s.wg.Done()
-		err := job.Task()
-		if job.OnFinished != nil {
-			job.OnFinished(err)
-		}
-		if s.OnCronJobFinished != nil {
-			s.OnCronJobFinished(job, err)
+// execCronJob runs the task of the job and its callbacks. Called by startCronRun in a goroutine
+func (s *Scheduler) execCronJob(ctx context.Context, job *CronJob, token int64) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[PANIC][Scheduler] recovered in the callbacks of job %s: %v", job.ID, r)
 		}
 	}()
+	attempts, err := s.runTask(ctx, taskRun{
+		jobID:   job.ID,
+		task:    job.Task,
+		timeout: job.Timeout,
+		retry:   job.Retry,
+		onRetry: job.OnRetry,
+		token:   token,
+	})
+	log.Printf("[DEBUG] cron job %s done after %d attempts", job.ID, attempts)
+	if job.OnFinished != nil {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Println("[PANIC] Recovered in job.OnFinished:", r)
+				}
+			}()
+			job.OnFinished(err, attempts)
+		}()
+	}
+	if s.OnCronJobFinished != nil {
+		s.OnCronJobFinished(job, err, attempts)
+	}
 }
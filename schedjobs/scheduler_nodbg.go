@@ -3,14 +3,16 @@
 package schedjobs
 
 import (
+	"context"
+	"log"
 	"time"
 )
 
-func (s *Scheduler) runOneTimeJobs(now time.Time) {
-	key := now.Unix() / 60
+// runOneTimeJobs runs the jobs of a slot due now
+func (s *Scheduler) runOneTimeJobs(slot int64) {
 	s.mu.Lock()
-	jobs := s.oneTimeJobs[key]
-	delete(s.oneTimeJobs, key)
+	jobs := s.oneTimeJobs[slot]
+	delete(s.oneTimeJobs, slot)
 	s.mu.Unlock()
 	for _, job := range jobs {
 		s.runOneTimeJob(job)
@@ -21,41 +23,74 @@ func (s *Scheduler) runOneTimeJob(job *OneTimeJob) {
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		err := job.Task()
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("[PANIC][Scheduler] recovered in the callbacks of job %s: %v", job.ID, r)
+			}
+		}()
+		token, ok := s.claimOneTimeRun(job)
+		if !ok {
+			log.Printf("[INFO][Scheduler] one-time job %s run by another instance", job.ID)
+			return
+		}
+		attempts, err := s.runTask(s.jobsCtx, taskRun{
+			jobID:   job.ID,
+			task:    job.Task,
+			timeout: job.Timeout,
+			retry:   job.Retry,
+			onRetry: job.OnRetry,
+			token:   token,
+		})
+		s.forgetJob(job)
 		if job.OnFinished != nil {
-			job.OnFinished(err)
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						log.Println("[PANIC] Recovered in job.OnFinished:", r)
+					}
+				}()
+				job.OnFinished(err, attempts)
+			}()
 		}
 		if s.OnOneTimeJobFinished != nil {
-			s.OnOneTimeJobFinished(job, err)
+			s.OnOneTimeJobFinished(job, err, attempts)
 		}
 	}()
 }
 
-func (s *Scheduler) runCronJobs(now time.Time) {
-	s.mu.Lock()
-	// Copy values to a slice so we can unlock early
-	jobs := make([]*CronJob, 0, len(s.cronJobs))
-	for _, job := range s.cronJobs {
-		jobs = append(jobs, job)
-	}
-	s.mu.Unlock()
-	for _, job := range jobs {
-		if job.Matches(now) {
-			s.runCronJob(job)
-		}
+// runCronJob runs the job due at, once across the instances if the scheduler has a Lease
+func (s *Scheduler) runCronJob(job *CronJob, at time.Time) {
+	if token, ok := s.claimCronRun(job, at); ok {
+		s.startCronRun(job, token)
 	}
 }
 
-func (s *Scheduler) runCronJob(job *CronJob) {
-	s.wg.Add(1)
-	go func() {
-		defer s.wg.Done()
-		err := job.Task()
-		if job.OnFinished != nil {
-			job.OnFinished(err)
-		}
-		if s.OnCronJobFinished != nil {
-			s.OnCronJobFinished(job, err)
+// execCronJob runs the task of the job and its callbacks. Called by startCronRun in a goroutine
+func (s *Scheduler) execCronJob(ctx context.Context, job *CronJob, token int64) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[PANIC][Scheduler] recovered in the callbacks of job %s: %v", job.ID, r)
 		}
 	}()
+	attempts, err := s.runTask(ctx, taskRun{
+		jobID:   job.ID,
+		task:    job.Task,
+		timeout: job.Timeout,
+		retry:   job.Retry,
+		onRetry: job.OnRetry,
+		token:   token,
+	})
+	if job.OnFinished != nil {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Println("[PANIC] Recovered in job.OnFinished:", r)
+				}
+			}()
+			job.OnFinished(err, attempts)
+		}()
+	}
+	if s.OnCronJobFinished != nil {
+		s.OnCronJobFinished(job, err, attempts)
+	}
 }
@@ -0,0 +1,102 @@
+package schedjobs
+
+import (
+	"container/heap"
+	"time"
+)
+
+// idleWait is how long the run loop sleeps without any timer. Adding a job wakes it earlier
+const idleWait = time.Hour
+
+// timer is a due time in the heap: a cron job's next run or a slot of one-time jobs.
+// Timers of deleted jobs and emptied slots stay in the heap and are dropped when due.
+type timer struct {
+	at   time.Time // wall clock. compared with time.Now() without the monotonic reading
+	cron *CronJob  // the cron job, or nil for a one-time slot
+	slot int64     // [one-time] key of the slot in oneTimeJobs
+}
+
+// timerHeap is a min-heap of timers by due time. guarded by Scheduler.mu
+type timerHeap []*timer
+
+func (h timerHeap) Len() int           { return len(h) }
+func (h timerHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h timerHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *timerHeap) Push(x any)        { *h = append(*h, x.(*timer)) }
+func (h *timerHeap) Pop() any {
+	old := *h
+	n := len(old)
+	t := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return t
+}
+
+// pushTimer adds a timer and wakes the run loop to re-arm. Call with s.mu held
+func (s *Scheduler) pushTimer(t *timer) {
+	heap.Push(&s.timers, t)
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// scheduleCronJob pushes the timer of the job's next run after t. Call with s.mu held
+func (s *Scheduler) scheduleCronJob(job *CronJob, t time.Time) {
+	next := job.NextAfter(t.In(job.location(s.Location)))
+	if next.IsZero() {
+		return // never matches
+	}
+	s.pushTimer(&timer{at: next, cron: job})
+}
+
+// nextWait returns how long to sleep until the earliest timer
+func (s *Scheduler) nextWait(now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.timers) == 0 {
+		return idleWait
+	}
+	return min(max(s.timers[0].at.Sub(now), 0), idleWait)
+}
+
+// popDue pops the timers due at now, rescheduling the cron jobs still registered.
+// A cron job that fell behind (e.g. the process stalled) runs once for its missed times, and resumes after now.
+func (s *Scheduler) popDue(now time.Time) []*timer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []*timer
+	for len(s.timers) > 0 && !s.timers[0].at.After(now) {
+		t := heap.Pop(&s.timers).(*timer)
+		if t.cron != nil {
+			if s.cronJobs[t.cron.ID] != t.cron {
+				continue // deleted or replaced
+			}
+			next := t.cron.NextAfter(t.at)
+			if !next.IsZero() && !next.After(now) {
+				next = t.cron.NextAfter(now.In(t.cron.location(s.Location)))
+			}
+			if !next.IsZero() {
+				heap.Push(&s.timers, &timer{at: next, cron: t.cron})
+			}
+		}
+		due = append(due, t)
+	}
+	return due
+}
+
+// NextRun returns the next due time of a cron job
+func (s *Scheduler) NextRun(jobID string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, exists := s.cronJobs[jobID]
+	if !exists {
+		return time.Time{}, false
+	}
+	for _, t := range s.timers {
+		if t.cron == job {
+			return t.at, true
+		}
+	}
+	return time.Time{}, false
+}
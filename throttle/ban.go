@@ -0,0 +1,85 @@
+package throttle
+
+import (
+	"sort"
+	"time"
+)
+
+// BanAllGroups as the group of a ban blocks the id in every group
+const BanAllGroups = "*"
+
+// Ban blocks a bucket id of a group until the time, regardless of its bucket
+type Ban struct {
+	Group string    `json:"group"` // group id or BanAllGroups
+	ID    string    `json:"id"`
+	Until time.Time `json:"until"`
+}
+
+type banKey struct {
+	group string
+	id    string
+}
+
+// Ban blocks id in the group (or BanAllGroups) until the time. Replaces an existing ban
+func (s *BucketStore) Ban(groupID string, id string, until time.Time) {
+	s.bans.Store(banKey{group: groupID, id: id}, until)
+}
+
+// Unban lifts the ban of id in the group. false if not banned
+func (s *BucketStore) Unban(groupID string, id string) bool {
+	_, ok := s.bans.LoadAndDelete(banKey{group: groupID, id: id})
+	return ok
+}
+
+// BannedUntil returns the end of the ban of id in the group, including a BanAllGroups ban
+func (s *BucketStore) BannedUntil(groupID string, id string, now time.Time) (time.Time, bool) {
+	var until time.Time
+	for _, key := range []banKey{{group: groupID, id: id}, {group: BanAllGroups, id: id}} {
+		if v, ok := s.bans.Load(key); ok {
+			if t := v.(time.Time); t.After(now) && t.After(until) {
+				until = t
+			}
+		}
+	}
+	return until, !until.IsZero()
+}
+
+// Bans returns the active bans sorted by group and id
+func (s *BucketStore) Bans(now time.Time) []Ban {
+	bans := []Ban{}
+	s.bans.Range(func(k, v any) bool {
+		key := k.(banKey)
+		if until := v.(time.Time); until.After(now) {
+			bans = append(bans, Ban{Group: key.group, ID: key.id, Until: until})
+		}
+		return true
+	})
+	sort.Slice(bans, func(i, j int) bool {
+		if bans[i].Group != bans[j].Group {
+			return bans[i].Group < bans[j].Group
+		}
+		return bans[i].ID < bans[j].ID
+	})
+	return bans
+}
+
+// cleanupBans removes the expired bans
+func (s *BucketStore) cleanupBans(now time.Time) {
+	s.bans.Range(func(k, v any) bool {
+		if !v.(time.Time).After(now) {
+			s.bans.Delete(k)
+		}
+		return true
+	})
+}
+
+// bannedDecision denies a banned id until the ban ends
+func bannedDecision(conf *BucketConf, until time.Time, now time.Time) Decision {
+	d := Decision{Algorithm: conf.algorithm(), Limit: conf.Burst, Banned: true}
+	if d.Algorithm != AlgoTokenBucket && d.Algorithm != AlgoGCRA {
+		d.Limit = conf.Limit
+	}
+	d.Reset = until.Sub(now)
+	d.RetryAfter = d.Reset
+	return d
+}
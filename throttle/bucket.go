@@ -28,13 +28,6 @@ func (b *Bucket) refill(now time.Time) {
 }
 
 func (b *Bucket) Allow(now time.Time) bool {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	b.refill(now)
-	if b.tokens <= 0 {
-		return false
-	}
-	b.tokens--
-	return true
+	d, _ := b.take(now, 1)
+	return d.Allowed
 }
@@ -1,9 +1,54 @@
 package throttle
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
+// Rate-limit Algorithms
+const (
+	AlgoTokenBucket   = "token_bucket"   // [Default] refills Increment tokens every IncrPeriod, up to Burst
+	AlgoGCRA          = "gcra"           // smooth: one request per IncrPeriod/Increment, with Burst requests of tolerance
+	AlgoSlidingLog    = "sliding_log"    // exact: at most Limit requests in any Window. keeps a timestamp per request
+	AlgoSlidingWindow = "sliding_window" // approximate: weighted counters of the current and previous Window
+	AlgoConcurrency   = "concurrency"    // at most Limit in-flight requests. released on handler completion
+)
 
 type BucketConf struct {
-	Burst      int           // maximum number of tokens in the bucket
-	Increment  int           // how many tokens to add each period
-	IncrPeriod time.Duration // how often to add Increment
+	Algorithm   string        // AlgoXXX. "" = AlgoTokenBucket
+	Burst       int           // [token_bucket, gcra] maximum number of tokens in the bucket
+	Increment   int           // [token_bucket, gcra] how many tokens to add each period
+	IncrPeriod  time.Duration // [token_bucket, gcra] how often to add Increment
+	Limit       int           // [sliding_log, sliding_window] requests per Window. [concurrency] max in-flight requests
+	Window      time.Duration // [sliding_log, sliding_window]
+	Distributed bool          // [token_bucket, gcra] keep the buckets in the KV DB (BucketStore.SetKVClient), shared by all instances
+	KeySource   string        // [route policies] what identifies a bucket: KeyIP, KeyUser, ...
+}
+
+func (c *BucketConf) algorithm() string {
+	if c.Algorithm == "" {
+		return AlgoTokenBucket
+	}
+	return c.Algorithm
+}
+
+// Validate checks the fields required by the Algorithm
+func (c *BucketConf) Validate() error {
+	switch c.algorithm() {
+	case AlgoTokenBucket, AlgoGCRA:
+		if c.Burst <= 0 || c.Increment <= 0 || c.IncrPeriod <= 0 {
+			return fmt.Errorf("%s: burst, increment and incr_period must be positive", c.algorithm())
+		}
+	case AlgoSlidingLog, AlgoSlidingWindow:
+		if c.Limit <= 0 || c.Window <= 0 {
+			return fmt.Errorf("%s: limit and window must be positive", c.algorithm())
+		}
+	case AlgoConcurrency:
+		if c.Limit <= 0 {
+			return fmt.Errorf("%s: limit must be positive", c.algorithm())
+		}
+	default:
+		return fmt.Errorf("unknown throttle algorithm: %q", c.Algorithm)
+	}
+	return nil
 }
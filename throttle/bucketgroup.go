@@ -7,17 +7,20 @@ import (
 
 type BucketGroup struct {
 	conf    *BucketConf
-	buckets *sync.Map // string -> *Bucket
+	buckets *sync.Map // string -> limiterState (*Bucket for AlgoTokenBucket)
 }
 
+// GetBucket returns the token bucket of id. Only for AlgoTokenBucket groups
 func (g *BucketGroup) GetBucket(id string) (*Bucket, bool) {
 	bAny, ok := g.buckets.Load(id)
 	if !ok {
 		return nil, false
 	}
-	return bAny.(*Bucket), true
+	b, ok := bAny.(*Bucket)
+	return b, ok
 }
 
+// SetBucket sets the token bucket of id. Only for AlgoTokenBucket groups
 func (g *BucketGroup) SetBucket(id string, tokens int, now time.Time) {
 	g.buckets.Store(id, &Bucket{
 		tokens:      tokens,
@@ -25,3 +28,27 @@ func (g *BucketGroup) SetBucket(id string, tokens int, now time.Time) {
 		parentGroup: g,
 	})
 }
+
+func (g *BucketGroup) Conf() *BucketConf {
+	return g.conf
+}
+
+// Acquire runs the group's algorithm for id with the cost, creating its state on the first request
+func (g *BucketGroup) Acquire(id string, cost int, now time.Time) (Decision, func()) {
+	d, release, _ := g.reserve(id, cost, now)
+	return d, release
+}
+
+// reserve is Acquire that also returns the function giving back the cost when allowed
+func (g *BucketGroup) reserve(id string, cost int, now time.Time) (Decision, func(), func()) {
+	stateAny, ok := g.buckets.Load(id)
+	if !ok {
+		stateAny, _ = g.buckets.LoadOrStore(id, newLimiterState(g, now))
+	}
+	state := stateAny.(limiterState)
+	d, release := state.take(now, cost)
+	if !d.Allowed {
+		return d, release, noRelease
+	}
+	return d, release, func() { state.refund(cost, now) }
+}
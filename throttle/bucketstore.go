@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"maps"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/logitools/gw/svc"
@@ -17,7 +19,13 @@ type BucketStore struct {
 	done             chan error         // Shutdown Error Channel
 	cleanupCycle     time.Duration
 	cleanupOlderThan time.Duration
-	groups           map[string]*BucketGroup
+	groups           atomic.Pointer[map[string]*BucketGroup] // [Hot Reload] copy-on-write. read without locking
+	routes           atomic.Pointer[[]RoutePolicy]           // [Hot Reload] ApplyConf
+	mu               sync.Mutex                              // serializes the writers of groups and routes
+	codeGroups       map[string]*BucketConf                  // set by SetBucketGroup. kept by ApplyConf. guarded by mu
+	kv               *kvBuckets                              // SetKVClient. nil = local buckets only
+	bans             sync.Map                                // banKey -> time.Time (until)
+	snapshotStorage  SnapshotStorage                         // SetSnapshotStorage. nil = no snapshot
 }
 
 func (s *BucketStore) Name() string {
@@ -26,15 +34,28 @@ func (s *BucketStore) Name() string {
 
 func NewBucketStore(parentCtx context.Context, cleanupCycle time.Duration, cleanupOlderThan time.Duration) *BucketStore {
 	svcCtx, svcCancel := context.WithCancel(parentCtx)
-	return &BucketStore{
+	s := &BucketStore{
 		Ctx:              svcCtx,
 		cancel:           svcCancel,
 		state:            svc.StateREADY,
 		done:             make(chan error, 1),
 		cleanupCycle:     cleanupCycle,
 		cleanupOlderThan: cleanupOlderThan,
-		groups:           make(map[string]*BucketGroup),
+		codeGroups:       make(map[string]*BucketConf),
 	}
+	s.groups.Store(&map[string]*BucketGroup{})
+	s.routes.Store(&[]RoutePolicy{})
+	return s
+}
+
+// bucketGroups returns the current groups snapshot. Never modify it
+func (s *BucketStore) bucketGroups() map[string]*BucketGroup {
+	return *s.groups.Load()
+}
+
+// CleanupOlderThan returns the idle time after which the buckets are discarded
+func (s *BucketStore) CleanupOlderThan() time.Duration {
+	return s.cleanupOlderThan
 }
 
 // Start starts a service that manages buckets
@@ -45,6 +66,7 @@ func (s *BucketStore) Start() error {
 	if s.state != svc.StateREADY {
 		return fmt.Errorf("cannot start. not ready")
 	}
+	s.loadSnapshot()
 	s.state = svc.StateRUNNING
 	log.Printf("[INFO][Throttle] cleanup service started cycle=%v exp=%v", s.cleanupCycle, s.cleanupOlderThan)
 	go s.run()
@@ -72,6 +94,7 @@ func (s *BucketStore) run() {
 		select {
 		case <-s.Ctx.Done():
 			log.Println("[INFO][Throttle] stopping cleaning service")
+			s.saveSnapshot()
 			s.done <- nil
 			return
 		case now := <-ticker.C:
@@ -83,43 +106,82 @@ func (s *BucketStore) run() {
 				}()
 				log.Printf("[INFO][Throttle] %v cleanup cycle ...", s.cleanupCycle)
 				s.Cleanup(now)
+				s.cleanupBans(now)
 			}()
 		}
 	}
 }
 
 func (s *BucketStore) GetBucketGroup(id string) (*BucketGroup, bool) {
-	g, ok := s.groups[id]
+	g, ok := s.bucketGroups()[id]
 	return g, ok
 }
 
 func (s *BucketStore) GetBucket(groupID string, bucketID string) (*Bucket, bool) {
-	g, ok := s.groups[groupID]
+	g, ok := s.bucketGroups()[groupID]
 	if !ok {
 		return nil, false
 	}
 	return g.GetBucket(bucketID)
 }
 
+// SetBucketGroup adds or replaces a group. Safe while serving
+// The group survives ApplyConf unless the conf declares a group of the same id
 func (s *BucketStore) SetBucketGroup(id string, conf *BucketConf) {
-	s.groups[id] = &BucketGroup{
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codeGroups[id] = conf
+	groups := maps.Clone(s.bucketGroups())
+	groups[id] = &BucketGroup{
 		conf:    conf,
 		buckets: &sync.Map{},
 	}
+	s.groups.Store(&groups)
+}
+
+// Allow takes a permit and releases it at once.
+// For AlgoConcurrency groups, use Acquire and release on completion.
+func (s *BucketStore) Allow(groupID string, bucketID string, now time.Time) Decision {
+	return s.AllowN(groupID, bucketID, 1, now)
+}
+
+// AllowN is Allow with the cost of the request
+func (s *BucketStore) AllowN(groupID string, bucketID string, cost int, now time.Time) Decision {
+	d, release := s.AcquireN(groupID, bucketID, cost, now)
+	release()
+	return d
+}
+
+// Acquire runs the algorithm of the group for bucketID. Call release when the request completes.
+func (s *BucketStore) Acquire(groupID string, bucketID string, now time.Time) (Decision, func()) {
+	return s.AcquireN(groupID, bucketID, 1, now)
 }
 
-func (s *BucketStore) Allow(groupID string, bucketID string, now time.Time) bool {
+// AcquireN is Acquire with the cost of the request. A cost below 1 counts as 1
+func (s *BucketStore) AcquireN(groupID string, bucketID string, cost int, now time.Time) (Decision, func()) {
+	d, release, _ := s.ReserveN(groupID, bucketID, cost, now)
+	return d, release
+}
+
+// ReserveN is AcquireN that also returns refund, which gives the cost back to the bucket.
+// Call refund instead of keeping the cost when the request does not proceed after all,
+// e.g. a later bucket group of the route denied it. refund is a no-op when denied.
+func (s *BucketStore) ReserveN(groupID string, bucketID string, cost int, now time.Time) (d Decision, release func(), refund func()) {
+	cost = max(cost, 1)
 	g, ok := s.GetBucketGroup(groupID)
 	if !ok {
-		return false // Invalid groupID always Blocked
+		return Decision{}, noRelease, noRelease // Invalid groupID always Blocked
+	}
+	if until, banned := s.BannedUntil(groupID, bucketID, now); banned {
+		return bannedDecision(g.conf, until, now), noRelease, noRelease
 	}
-	b, ok := g.GetBucket(bucketID)
-	if ok {
-		return b.Allow(now)
+	if d, ok := s.allowKV(g, groupID, bucketID, cost, now); ok {
+		if !d.Allowed {
+			return d, noRelease, noRelease
+		}
+		return d, noRelease, func() { s.refundKV(g, groupID, bucketID, cost) }
 	}
-	// consume 1 token from the fresh bucket
-	g.SetBucket(bucketID, g.conf.Burst-1, now)
-	return true
+	return g.reserve(bucketID, cost, now)
 }
 
 // Inspect returns a snapshot of all BucketGroup IDs and their local Bucket IDs.
@@ -128,7 +190,7 @@ func (s *BucketStore) Allow(groupID string, bucketID string, now time.Time) bool
 func (s *BucketStore) Inspect() map[string][]string {
 	result := make(map[string][]string)
 
-	for groupID, bucketGroup := range s.groups {
+	for groupID, bucketGroup := range s.bucketGroups() {
 		var ids []string
 		bucketGroup.buckets.Range(func(localID, _ any) bool {
 			ids = append(ids, localID.(string))
@@ -139,3 +201,36 @@ func (s *BucketStore) Inspect() map[string][]string {
 
 	return result
 }
+
+// InspectBucket returns the local state of bucketID in the group.
+// For distributed groups, the state in the KV DB is not included.
+func (s *BucketStore) InspectBucket(groupID string, bucketID string) (BucketSnapshot, bool) {
+	g, ok := s.GetBucketGroup(groupID)
+	if !ok {
+		return BucketSnapshot{}, false
+	}
+	stateAny, ok := g.buckets.Load(bucketID)
+	if !ok {
+		return BucketSnapshot{}, false
+	}
+	b := stateAny.(limiterState).snapshot()
+	b.Group = groupID
+	b.ID = bucketID
+	return b, true
+}
+
+// ResetBucket drops the state of bucketID in the group, so that its next request starts with a full quota.
+// In-flight requests of a concurrency group are forgotten. Also deletes the KV bucket of a distributed group.
+func (s *BucketStore) ResetBucket(ctx context.Context, groupID string, bucketID string) (found bool, err error) {
+	g, ok := s.GetBucketGroup(groupID)
+	if !ok {
+		return false, fmt.Errorf("bucket group not found: %s", groupID)
+	}
+	_, found = g.buckets.LoadAndDelete(bucketID)
+	if s.kv != nil && g.conf.Distributed {
+		var n int64
+		n, err = s.kv.client.Delete(ctx, s.kv.key(groupID, bucketID))
+		found = found || n > 0
+	}
+	return found, err
+}
@@ -10,18 +10,16 @@ import (
 func (s *BucketStore) Cleanup(now time.Time) {
 	log.Printf("[DEBUG][Throttle] cleaning Buckets older than %v", s.cleanupOlderThan)
 	cleanCnt := 0
-	for gid, g := range s.groups {
+	for gid, g := range s.bucketGroups() {
 		log.Printf("[DEBUG][Throttle] cleaning BucketGroup %q", gid)
 		g.buckets.Range(func(id, value any) bool {
-			b := value.(*Bucket)
+			b := value.(limiterState)
 
-			// lock per bucket while checking/removing
-			b.mu.Lock()
-			last := b.lastCheck
-			log.Printf("[DEBUG][Throttle] inspecting Bucket id=%q lastCheck=%v", id, last)
-			b.mu.Unlock()
+			// lock per bucket while checking
+			last := b.lastUsed()
+			log.Printf("[DEBUG][Throttle] inspecting Bucket id=%q lastUsed=%v", id, last)
 
-			if now.Sub(last) > s.cleanupOlderThan {
+			if now.Sub(last) > s.cleanupOlderThan && !b.busy() {
 				g.buckets.Delete(id)
 				cleanCnt++
 				log.Println("[DEBUG][Throttle] Bucket REMOVED")
@@ -7,14 +7,11 @@ import (
 )
 
 func (s *BucketStore) Cleanup(now time.Time) {
-	for _, g := range s.groups {
+	for _, g := range s.bucketGroups() {
 		g.buckets.Range(func(id, value any) bool {
-			b := value.(*Bucket)
-			// lock per bucket while checking/removing
-			b.mu.Lock()
-			last := b.lastCheck
-			b.mu.Unlock()
-			if now.Sub(last) > s.cleanupOlderThan {
+			b := value.(limiterState)
+			// lock per bucket while checking
+			if now.Sub(b.lastUsed()) > s.cleanupOlderThan && !b.busy() {
 				g.buckets.Delete(id)
 			}
 			return true // continue iteration
@@ -0,0 +1,20 @@
+package throttle
+
+import "context"
+
+// Ctx Access Helpers
+
+type costKey struct{}
+
+// WithCost sets the throttle cost of the request, e.g. by the ThrottleCost handler wrapper on an expensive route
+func WithCost(ctx context.Context, cost int) context.Context {
+	return context.WithValue(ctx, costKey{}, cost)
+}
+
+// CostFromContext returns the cost set by WithCost, or 1 if not set
+func CostFromContext(ctx context.Context) int {
+	if cost, ok := ctx.Value(costKey{}).(int); ok && cost > 0 {
+		return cost
+	}
+	return 1
+}
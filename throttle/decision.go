@@ -0,0 +1,24 @@
+package throttle
+
+import "time"
+
+// Decision is the outcome of a throttle check, with the quota state to report to the client
+type Decision struct {
+	Allowed    bool
+	Algorithm  string        // AlgoXXX of the bucket group. "" for an unknown group
+	Limit      int           // requests allowed in a burst (or in flight for AlgoConcurrency)
+	Remaining  int           // requests left right after this one
+	Reset      time.Duration // until the quota is fully restored. 0 if already full or unknown
+	RetryAfter time.Duration // [denied] until the next request may be allowed. 0 if unknown
+	Banned     bool          // [denied] by a ban rather than the bucket
+}
+
+// ResetAt returns the time the quota is fully restored
+func (d Decision) ResetAt(now time.Time) time.Time {
+	return now.Add(d.Reset)
+}
+
+// ceilDiv for non-negative a, positive b
+func ceilDiv(a int, b int) int {
+	return (a + b - 1) / b
+}
@@ -0,0 +1,225 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/logitools/gw/db/kvdb"
+)
+
+const (
+	DefaultKVKeyPrefix  = "throttle:"
+	DefaultKVTimeout    = 100 * time.Millisecond // per KV call, then the local bucket is used
+	DefaultKVRetryAfter = 5 * time.Second        // local buckets only for this long after a KV failure
+)
+
+// kvTakeScript refills the bucket in whole IncrPeriod steps (the same as Bucket.refill) and takes cost tokens atomically.
+// The server clock is used so that all instances agree on the time.
+// KEYS[1] = bucket key. ARGV = burst, increment, period (ms), ttl (ms), cost.
+// Returns {allowed (1|0), remaining tokens, reset (ms), retry after (ms)}.
+const kvTakeScript = `
+local burst = tonumber(ARGV[1])
+local incr = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+local cost = tonumber(ARGV[5])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(state[1])
+local last = tonumber(state[2])
+if tokens == nil or last == nil then
+  tokens = burst
+  last = now
+else
+  local elapsed = now - last
+  if elapsed >= period then
+    local times = math.floor(elapsed / period)
+    tokens = math.min(burst, tokens + times * incr)
+    last = last + times * period
+  end
+end
+local allowed = 0
+if tokens >= cost then
+  tokens = tokens - cost
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tokens, 'last', last)
+redis.call('PEXPIRE', KEYS[1], ttl)
+local reset = 0
+if tokens < burst then
+  reset = math.max(last + math.ceil((burst - tokens) / incr) * period - now, 0)
+end
+local retry = 0
+if allowed == 0 then
+  retry = math.max(last + math.ceil((cost - tokens) / incr) * period - now, 0)
+end
+return {allowed, tokens, reset, retry}
+`
+
+// kvGCRAScript stores the theoretical arrival time (TAT, microseconds) of GCRA in the KV DB.
+// KEYS[1] = bucket key. ARGV = emission interval (us), burst, ttl (ms), cost.
+// Returns {allowed (1|0), remaining, reset (us), retry after (us)}.
+const kvGCRAScript = `
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[3])
+local cost = tonumber(ARGV[4])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if tat == nil or tat < now then
+  tat = now
+end
+local tolerance = interval * burst
+local newTAT = tat + interval * cost
+if newTAT - now > tolerance then
+  return {0, 0, tat - now, newTAT - now - tolerance}
+end
+redis.call('SET', KEYS[1], newTAT, 'PX', ttl)
+return {1, math.floor((tolerance - (newTAT - now)) / interval), newTAT - now, 0}
+`
+
+// kvRefundScript gives cost tokens back to a token bucket, up to burst. Expired buckets are full already.
+// KEYS[1] = bucket key. ARGV = burst, cost.
+const kvRefundScript = `
+local tokens = tonumber(redis.call('HGET', KEYS[1], 'tokens'))
+if tokens ~= nil then
+  redis.call('HSET', KEYS[1], 'tokens', math.min(tonumber(ARGV[1]), tokens + tonumber(ARGV[2])))
+end
+return 1
+`
+
+// kvGCRARefundScript moves the TAT back by cost emission intervals, keeping the expiry.
+// KEYS[1] = bucket key. ARGV = emission interval (us), cost.
+const kvGCRARefundScript = `
+local tat = tonumber(redis.call('GET', KEYS[1]))
+local ttl = redis.call('PTTL', KEYS[1])
+if tat ~= nil and ttl > 0 then
+  redis.call('SET', KEYS[1], tat - tonumber(ARGV[1]) * tonumber(ARGV[2]), 'PX', ttl)
+end
+return 1
+`
+
+// kvBuckets keeps the token-bucket state of Distributed bucket groups in the KV DB
+type kvBuckets struct {
+	client     kvdb.Client
+	keyPrefix  string
+	timeout    time.Duration
+	retryAfter time.Duration
+	downUntil  atomic.Int64 // unix nano. KV is skipped until then after a failure
+}
+
+func (k *kvBuckets) key(groupID string, bucketID string) string {
+	return k.keyPrefix + groupID + ":" + bucketID
+}
+
+// available reports whether the KV is not in its back-off window after a failure
+func (k *kvBuckets) available(now time.Time) bool {
+	return now.UnixNano() >= k.downUntil.Load()
+}
+
+// markDown starts the back-off window. Logs only on the transition to avoid flooding
+func (k *kvBuckets) markDown(now time.Time, err error) {
+	prev := k.downUntil.Swap(now.Add(k.retryAfter).UnixNano())
+	if prev <= now.UnixNano() {
+		log.Printf("[WARN][Throttle] KV unreachable, using local buckets for %v: %v", k.retryAfter, err)
+	}
+}
+
+func (k *kvBuckets) allow(ctx context.Context, groupID string, bucketID string, conf *BucketConf, cost int, ttl time.Duration) (Decision, error) {
+	ctx, cancel := context.WithTimeout(ctx, k.timeout)
+	defer cancel()
+	keys := []string{k.key(groupID, bucketID)}
+	d := Decision{Algorithm: conf.algorithm(), Limit: conf.Burst}
+	var (
+		res  any
+		err  error
+		unit time.Duration
+	)
+	switch d.Algorithm {
+	case AlgoGCRA:
+		interval := conf.IncrPeriod / time.Duration(max(conf.Increment, 1))
+		res, err = k.client.Eval(ctx, kvGCRAScript, keys, interval.Microseconds(), conf.Burst, ttl.Milliseconds(), cost)
+		unit = time.Microsecond
+	default:
+		res, err = k.client.Eval(ctx, kvTakeScript, keys, conf.Burst, conf.Increment, conf.IncrPeriod.Milliseconds(), ttl.Milliseconds(), cost)
+		unit = time.Millisecond
+	}
+	if err != nil {
+		return d, err
+	}
+	reply, ok := res.([]any)
+	if !ok || len(reply) != 4 {
+		return d, fmt.Errorf("unexpected script reply: %v", res)
+	}
+	nums := make([]int64, len(reply))
+	for i, v := range reply {
+		if nums[i], ok = v.(int64); !ok {
+			return d, fmt.Errorf("unexpected script reply: %v", res)
+		}
+	}
+	d.Allowed = nums[0] == 1
+	d.Remaining = int(nums[1])
+	d.Reset = time.Duration(nums[2]) * unit
+	d.RetryAfter = time.Duration(nums[3]) * unit
+	return d, nil
+}
+
+func (k *kvBuckets) refund(ctx context.Context, groupID string, bucketID string, conf *BucketConf, cost int) error {
+	ctx, cancel := context.WithTimeout(ctx, k.timeout)
+	defer cancel()
+	keys := []string{k.key(groupID, bucketID)}
+	var err error
+	switch conf.algorithm() {
+	case AlgoGCRA:
+		interval := conf.IncrPeriod / time.Duration(max(conf.Increment, 1))
+		_, err = k.client.Eval(ctx, kvGCRARefundScript, keys, interval.Microseconds(), cost)
+	default:
+		_, err = k.client.Eval(ctx, kvRefundScript, keys, conf.Burst, cost)
+	}
+	return err
+}
+
+// SetKVClient stores the buckets of the Distributed bucket groups in the KV DB, shared by all instances.
+// keyPrefix defaults to DefaultKVKeyPrefix.
+// When the KV DB fails, local buckets are used for DefaultKVRetryAfter before trying the KV DB again.
+// Call before Start.
+func (s *BucketStore) SetKVClient(client kvdb.Client, keyPrefix string) {
+	if keyPrefix == "" {
+		keyPrefix = DefaultKVKeyPrefix
+	}
+	s.kv = &kvBuckets{
+		client:     client,
+		keyPrefix:  keyPrefix,
+		timeout:    DefaultKVTimeout,
+		retryAfter: DefaultKVRetryAfter,
+	}
+}
+
+// allowKV takes cost tokens from the KV bucket. ok = false if the local bucket has to be used instead.
+func (s *BucketStore) allowKV(g *BucketGroup, groupID string, bucketID string, cost int, now time.Time) (d Decision, ok bool) {
+	if s.kv == nil || !g.conf.Distributed || !s.kv.available(now) {
+		return d, false
+	}
+	if algo := g.conf.algorithm(); algo != AlgoTokenBucket && algo != AlgoGCRA {
+		return d, false // local only
+	}
+	ttl := max(s.cleanupOlderThan, g.conf.IncrPeriod) // idle KV buckets expire like cleaned-up local buckets
+	d, err := s.kv.allow(s.Ctx, groupID, bucketID, g.conf, cost, ttl)
+	if err != nil {
+		s.kv.markDown(now, err)
+		return d, false
+	}
+	return d, true
+}
+
+// refundKV gives cost tokens back to the KV bucket. A failure only loses the refund
+func (s *BucketStore) refundKV(g *BucketGroup, groupID string, bucketID string, cost int) {
+	if err := s.kv.refund(s.Ctx, groupID, bucketID, g.conf, cost); err != nil {
+		log.Printf("[WARN][Throttle] KV refund failed, group=%s: %v", groupID, err)
+	}
+}
@@ -0,0 +1,320 @@
+package throttle
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Limiter decides whether a request identified by (groupID, id) may proceed.
+// release must be called once the request completes. It frees the in-flight slot of concurrency limits
+// and is a no-op for the rate-limit algorithms.
+type Limiter interface {
+	Acquire(groupID string, id string, now time.Time) (decision Decision, release func())
+	// AcquireN takes cost permits at once. e.g. an expensive endpoint costing several requests
+	AcquireN(groupID string, id string, cost int, now time.Time) (decision Decision, release func())
+}
+
+// Ensure BucketStore implements Limiter
+var _ Limiter = (*BucketStore)(nil)
+
+func noRelease() {}
+
+// limiterState is the per-id state of a BucketGroup's algorithm
+type limiterState interface {
+	take(now time.Time, cost int) (Decision, func())
+	refund(cost int, at time.Time) // gives back the cost of an allowed take at the time. e.g. when another group denied the request
+	lastUsed() time.Time
+	busy() bool // holds in-flight permits. never cleaned up while busy
+	snapshot() BucketSnapshot
+}
+
+func newLimiterState(g *BucketGroup, now time.Time) limiterState {
+	conf := g.conf
+	switch conf.algorithm() {
+	case AlgoGCRA:
+		return &gcraState{conf: conf, tat: now, seen: now}
+	case AlgoSlidingLog:
+		return &slidingLogState{conf: conf, seen: now}
+	case AlgoSlidingWindow:
+		return &slidingWindowState{conf: conf, start: now.Truncate(conf.Window), seen: now}
+	case AlgoConcurrency:
+		return &concurrencyState{conf: conf, seen: now}
+	default:
+		return &Bucket{tokens: conf.Burst, lastCheck: now, parentGroup: g}
+	}
+}
+
+//---- Token Bucket ----
+
+func (b *Bucket) take(now time.Time, cost int) (Decision, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	conf := b.parentGroup.conf
+	b.refill(now)
+	d := Decision{Algorithm: AlgoTokenBucket, Limit: conf.Burst}
+	if b.tokens >= cost {
+		b.tokens -= cost
+		d.Allowed = true
+	}
+	d.Remaining = b.tokens
+	if need := conf.Burst - b.tokens; need > 0 {
+		periods := ceilDiv(need, max(conf.Increment, 1))
+		d.Reset = max(b.lastCheck.Add(time.Duration(periods)*conf.IncrPeriod).Sub(now), 0)
+	}
+	if !d.Allowed {
+		periods := ceilDiv(cost-b.tokens, max(conf.Increment, 1))
+		d.RetryAfter = max(b.lastCheck.Add(time.Duration(periods)*conf.IncrPeriod).Sub(now), 0)
+	}
+	return d, noRelease
+}
+
+func (b *Bucket) refund(cost int, _ time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.tokens+cost, b.parentGroup.conf.Burst)
+}
+
+func (b *Bucket) lastUsed() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastCheck
+}
+
+func (b *Bucket) busy() bool {
+	return false
+}
+
+//---- GCRA ----
+
+// gcraState tracks the theoretical arrival time (TAT) of the next request.
+// Each request moves TAT by the emission interval IncrPeriod/Increment.
+// A request is allowed if TAT stays within Burst intervals ahead of now.
+type gcraState struct {
+	mu   sync.Mutex
+	conf *BucketConf
+	tat  time.Time
+	seen time.Time
+}
+
+func (s *gcraState) take(now time.Time, cost int) (Decision, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seen = now
+	interval := s.conf.IncrPeriod / time.Duration(max(s.conf.Increment, 1))
+	tolerance := interval * time.Duration(s.conf.Burst)
+	tat := s.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	newTAT := tat.Add(interval * time.Duration(cost))
+	d := Decision{Algorithm: AlgoGCRA, Limit: s.conf.Burst}
+	if newTAT.Sub(now) > tolerance {
+		d.Reset = tat.Sub(now)
+		d.RetryAfter = newTAT.Sub(now) - tolerance
+		return d, noRelease
+	}
+	s.tat = newTAT
+	d.Allowed = true
+	d.Remaining = int((tolerance - newTAT.Sub(now)) / interval)
+	d.Reset = newTAT.Sub(now)
+	return d, noRelease
+}
+
+func (s *gcraState) refund(cost int, _ time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	interval := s.conf.IncrPeriod / time.Duration(max(s.conf.Increment, 1))
+	s.tat = s.tat.Add(-interval * time.Duration(cost)) // a TAT in the past is reset to now by the next take
+}
+
+func (s *gcraState) lastUsed() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seen
+}
+
+func (s *gcraState) busy() bool {
+	return false
+}
+
+//---- Sliding Window Log ----
+
+type slidingLogState struct {
+	mu    sync.Mutex
+	conf  *BucketConf
+	times []time.Time // allowed request times in the window, oldest first
+	seen  time.Time
+}
+
+func (s *slidingLogState) take(now time.Time, cost int) (Decision, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seen = now
+	cutoff := now.Add(-s.conf.Window)
+	i := 0
+	for i < len(s.times) && !s.times[i].After(cutoff) {
+		i++
+	}
+	s.times = s.times[i:]
+	d := Decision{Algorithm: AlgoSlidingLog, Limit: s.conf.Limit}
+	if len(s.times)+cost > s.conf.Limit {
+		if len(s.times) > 0 {
+			d.Reset = s.times[len(s.times)-1].Add(s.conf.Window).Sub(now)
+			// enough of the oldest ones leave the window
+			d.RetryAfter = s.times[min(len(s.times)+cost-s.conf.Limit, len(s.times))-1].Add(s.conf.Window).Sub(now)
+		}
+		return d, noRelease
+	}
+	for range cost {
+		s.times = append(s.times, now)
+	}
+	d.Allowed = true
+	d.Remaining = s.conf.Limit - len(s.times)
+	d.Reset = s.conf.Window
+	return d, noRelease
+}
+
+// refund drops cost entries of the take time, newest first. Entries already out of the window are gone anyway
+func (s *slidingLogState) refund(cost int, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.times) - 1; i >= 0 && cost > 0; i-- {
+		if s.times[i].Equal(at) {
+			s.times = slices.Delete(s.times, i, i+1)
+			cost--
+		}
+	}
+}
+
+func (s *slidingLogState) lastUsed() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seen
+}
+
+func (s *slidingLogState) busy() bool {
+	return false
+}
+
+//---- Sliding Window Counter ----
+
+// slidingWindowState estimates the requests in the last Window as
+// prev * (remaining fraction of the previous window) + curr
+type slidingWindowState struct {
+	mu    sync.Mutex
+	conf  *BucketConf
+	start time.Time // start of the current window
+	prev  int
+	curr  int
+	seen  time.Time
+}
+
+func (s *slidingWindowState) take(now time.Time, cost int) (Decision, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seen = now
+	window := s.conf.Window
+	if elapsed := now.Sub(s.start); elapsed >= window {
+		if elapsed >= 2*window {
+			s.prev = 0
+		} else {
+			s.prev = s.curr
+		}
+		s.curr = 0
+		s.start = now.Truncate(window)
+	}
+	limit := float64(s.conf.Limit)
+	weight := 1 - float64(now.Sub(s.start))/float64(window)
+	estimate := float64(s.prev)*weight + float64(s.curr)
+	d := Decision{Algorithm: AlgoSlidingWindow, Limit: s.conf.Limit, Reset: s.start.Add(2 * window).Sub(now)}
+	if estimate+float64(cost) > limit {
+		// when the weighted estimate drops below the limit
+		var retryAt time.Time
+		room := limit - float64(cost)
+		switch {
+		case room < 0:
+			// never fits. retry after both windows passed
+			retryAt = s.start.Add(2 * window)
+		case float64(s.curr) > room:
+			// in the next window, curr becomes prev: curr * (1 - e/window) <= room
+			retryAt = s.start.Add(window + time.Duration(float64(window)*(1-room/float64(s.curr))))
+		default:
+			// prev * (1 - e/window) + curr <= room
+			retryAt = s.start.Add(time.Duration(float64(window) * (1 - (room-float64(s.curr))/float64(s.prev))))
+		}
+		d.RetryAfter = max(retryAt.Sub(now), 0)
+		return d, noRelease
+	}
+	s.curr += cost
+	d.Allowed = true
+	d.Remaining = max(int(limit-estimate)-cost, 0)
+	return d, noRelease
+}
+
+// refund takes the cost off the window the take counted in: the current one, or the previous one after a roll-over
+func (s *slidingWindowState) refund(cost int, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case !at.Before(s.start):
+		s.curr = max(s.curr-cost, 0)
+	case !at.Before(s.start.Add(-s.conf.Window)):
+		s.prev = max(s.prev-cost, 0)
+	}
+}
+
+func (s *slidingWindowState) lastUsed() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seen
+}
+
+func (s *slidingWindowState) busy() bool {
+	return false
+}
+
+//---- Concurrency ----
+
+type concurrencyState struct {
+	mu       sync.Mutex
+	conf     *BucketConf
+	inFlight int
+	seen     time.Time
+}
+
+func (s *concurrencyState) take(now time.Time, cost int) (Decision, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seen = now
+	d := Decision{Algorithm: AlgoConcurrency, Limit: s.conf.Limit}
+	if s.inFlight+cost > s.conf.Limit {
+		return d, noRelease // no way to know when a slot frees up
+	}
+	s.inFlight += cost
+	d.Allowed = true
+	d.Remaining = s.conf.Limit - s.inFlight
+	var once sync.Once
+	return d, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			s.inFlight -= cost
+			s.mu.Unlock()
+		})
+	}
+}
+
+// refund is a no-op. The slot is freed by the release of the take
+func (s *concurrencyState) refund(int, time.Time) {}
+
+func (s *concurrencyState) lastUsed() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seen
+}
+
+func (s *concurrencyState) busy() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inFlight > 0
+}
@@ -0,0 +1,183 @@
+package throttle
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Key Sources of a bucket group applied by route policies
+const (
+	KeyIP           = "ip"        // client IP
+	KeyUser         = "user"      // user id
+	KeySession      = "session"   // cookie session id
+	KeyClientID     = "client_id" // Client-Id header of the client app
+	KeyHeaderPrefix = "header:"   // "header:<Name>" value of the request header
+)
+
+// Conf is loaded from config/.throttle.json
+type Conf struct {
+	SnapshotFile  string                `json:"snapshot_file"`   // bucket snapshot across restarts. relative to the app root. not reloadable
+	SnapshotKVKey string                `json:"snapshot_kv_key"` // bucket snapshot in the KV DB instead of the file. per node. not reloadable
+	Groups        map[string]*GroupConf `json:"groups"`          // group id -> conf
+	Routes        []RoutePolicy         `json:"routes"`
+}
+
+// GroupConf declares a bucket group
+type GroupConf struct {
+	Algorithm   string `json:"algorithm"`   // AlgoXXX. "" = token_bucket
+	Burst       int    `json:"burst"`       // [token_bucket, gcra]
+	Increment   int    `json:"increment"`   // [token_bucket, gcra] tokens per incr_period
+	IncrPeriod  int    `json:"incr_period"` // [token_bucket, gcra] milliseconds
+	Limit       int    `json:"limit"`       // [sliding_log, sliding_window] requests per window. [concurrency] in flight
+	Window      int    `json:"window"`      // [sliding_log, sliding_window] milliseconds
+	Distributed bool   `json:"distributed"` // [token_bucket, gcra] buckets in the KV DB
+	Key         string `json:"key"`         // key source: ip, user, session, client_id, header:<Name>
+}
+
+func (g *GroupConf) BucketConf() *BucketConf {
+	return &BucketConf{
+		Algorithm:   g.Algorithm,
+		Burst:       g.Burst,
+		Increment:   g.Increment,
+		IncrPeriod:  time.Duration(g.IncrPeriod) * time.Millisecond,
+		Limit:       g.Limit,
+		Window:      time.Duration(g.Window) * time.Millisecond,
+		Distributed: g.Distributed,
+		KeySource:   g.Key,
+	}
+}
+
+// RoutePolicy applies bucket groups to the routes of a pattern.
+// Pattern is compared with the registered ServeMux pattern of the request (http.Request.Pattern),
+// e.g. "GET /api/items/{id}". A trailing "*" matches by prefix, and "*" alone matches every route.
+// Cost is the number of permits a request of the route takes from each group.
+// When several policies match, the highest cost applies.
+type RoutePolicy struct {
+	Pattern string   `json:"pattern"`
+	Groups  []string `json:"groups"` // group ids, checked in order
+	Cost    int      `json:"cost"`   // 0 = the cost in the request ctx (throttle.WithCost), or 1
+}
+
+func (p *RoutePolicy) matches(pattern string) bool {
+	if prefix, ok := strings.CutSuffix(p.Pattern, "*"); ok {
+		return strings.HasPrefix(pattern, prefix)
+	}
+	return p.Pattern == pattern
+}
+
+func validKeySource(key string) bool {
+	switch key {
+	case KeyIP, KeyUser, KeySession, KeyClientID:
+		return true
+	}
+	name, ok := strings.CutPrefix(key, KeyHeaderPrefix)
+	return ok && name != ""
+}
+
+// Validate checks the groups and that the routes only refer to declared groups
+func (c *Conf) Validate() error {
+	return c.validate(nil)
+}
+
+// validate is Validate with the routes also allowed to refer to the groups in otherGroups
+func (c *Conf) validate(otherGroups map[string]*BucketConf) error {
+	for id, g := range c.Groups {
+		if g == nil {
+			return fmt.Errorf("throttle group %q: empty", id)
+		}
+		if err := g.BucketConf().Validate(); err != nil {
+			return fmt.Errorf("throttle group %q: %w", id, err)
+		}
+		if !validKeySource(g.Key) {
+			return fmt.Errorf("throttle group %q: invalid key %q", id, g.Key)
+		}
+	}
+	for _, route := range c.Routes {
+		if route.Pattern == "" {
+			return fmt.Errorf("throttle route: empty pattern")
+		}
+		if route.Cost < 0 {
+			return fmt.Errorf("throttle route %q: negative cost", route.Pattern)
+		}
+		for _, id := range route.Groups {
+			_, declared := c.Groups[id]
+			if _, other := otherGroups[id]; !declared && !other {
+				return fmt.Errorf("throttle route %q: group %q not declared", route.Pattern, id)
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyConf validates conf and swaps in its groups and route policies, safe while serving.
+// The groups set by SetBucketGroup are kept, and the routes may refer to them. A conf group of the same id overrides one.
+// Other groups not in conf are removed.
+// A group whose conf is unchanged keeps its buckets, so reloading does not reset the counters.
+func (s *BucketStore) ApplyConf(conf *Conf) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := conf.validate(s.codeGroups); err != nil {
+		return err
+	}
+	current := s.bucketGroups()
+	groups := make(map[string]*BucketGroup, len(s.codeGroups)+len(conf.Groups))
+	keep := func(id string, bucketConf *BucketConf) {
+		if cur, ok := current[id]; ok && *cur.conf == *bucketConf {
+			groups[id] = cur
+			return
+		}
+		groups[id] = &BucketGroup{conf: bucketConf, buckets: &sync.Map{}}
+	}
+	for id, bucketConf := range s.codeGroups {
+		if _, overridden := conf.Groups[id]; !overridden {
+			keep(id, bucketConf)
+		}
+	}
+	for id, g := range conf.Groups {
+		keep(id, g.BucketConf())
+	}
+	routes := append([]RoutePolicy(nil), conf.Routes...)
+	s.groups.Store(&groups)
+	s.routes.Store(&routes)
+	return nil
+}
+
+// RouteGroups returns the ids of the groups applied to the route pattern, in policy order without duplicates
+func (s *BucketStore) RouteGroups(pattern string) []string {
+	ids, _ := s.RouteGroupsCost(pattern)
+	return ids
+}
+
+// RouteGroupsCost is RouteGroups with the highest cost of the matched policies. cost = 0 if none is set
+func (s *BucketStore) RouteGroupsCost(pattern string) (ids []string, cost int) {
+	seen := make(map[string]struct{})
+	for _, route := range *s.routes.Load() {
+		if !route.matches(pattern) {
+			continue
+		}
+		cost = max(cost, route.Cost)
+		for _, id := range route.Groups {
+			if _, dup := seen[id]; !dup {
+				seen[id] = struct{}{}
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, cost
+}
+
+// RoutePolicies returns a copy of the current route policies
+func (s *BucketStore) RoutePolicies() []RoutePolicy {
+	return append([]RoutePolicy(nil), *s.routes.Load()...)
+}
+
+// BucketConfs returns a copy of the current group confs
+func (s *BucketStore) BucketConfs() map[string]BucketConf {
+	confs := make(map[string]BucketConf)
+	for id, g := range s.bucketGroups() {
+		confs[id] = *g.conf
+	}
+	return confs
+}
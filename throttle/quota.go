@@ -0,0 +1,199 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/logitools/gw/db/kvdb"
+)
+
+const DefaultQuotaKeyPrefix = "quota:"
+
+// Quota Periods
+const (
+	QuotaDaily   = "daily"
+	QuotaMonthly = "monthly"
+)
+
+// quotaGrace keeps a counter for a while after its period ends, for inspection
+const quotaGrace = time.Hour
+
+// kvQuotaScript checks both limits and adds the cost to both counters atomically.
+// Nothing is counted when a limit would be exceeded.
+// KEYS = daily key, monthly key. ARGV = cost, daily limit, monthly limit (0 = unlimited), daily ttl (ms), monthly ttl (ms).
+// Returns {allowed (1|0), exceeded (0 = none, 1 = daily, 2 = monthly), daily used, monthly used}.
+const kvQuotaScript = `
+local cost = tonumber(ARGV[1])
+local dailyLimit = tonumber(ARGV[2])
+local monthlyLimit = tonumber(ARGV[3])
+local daily = tonumber(redis.call('GET', KEYS[1]) or '0')
+local monthly = tonumber(redis.call('GET', KEYS[2]) or '0')
+if dailyLimit > 0 and daily + cost > dailyLimit then
+  return {0, 1, daily, monthly}
+end
+if monthlyLimit > 0 and monthly + cost > monthlyLimit then
+  return {0, 2, daily, monthly}
+end
+daily = redis.call('INCRBY', KEYS[1], cost)
+if redis.call('PTTL', KEYS[1]) < 0 then
+  redis.call('PEXPIRE', KEYS[1], ARGV[4])
+end
+monthly = redis.call('INCRBY', KEYS[2], cost)
+if redis.call('PTTL', KEYS[2]) < 0 then
+  redis.call('PEXPIRE', KEYS[2], ARGV[5])
+end
+return {1, 0, daily, monthly}
+`
+
+// QuotaUsage is the counter of a client in the current period
+type QuotaUsage struct {
+	Period string    // QuotaDaily or QuotaMonthly
+	Used   int64     // cost counted in the period
+	Limit  int64     // 0 = unlimited
+	Reset  time.Time // start of the next period
+}
+
+// Remaining returns the cost left in the period. -1 if unlimited
+func (u QuotaUsage) Remaining() int64 {
+	if u.Limit <= 0 {
+		return -1
+	}
+	return max(u.Limit-u.Used, 0)
+}
+
+// QuotaDecision is the outcome of a quota check
+type QuotaDecision struct {
+	Allowed  bool
+	Exceeded string // [denied] the period whose limit would be exceeded
+	Daily    QuotaUsage
+	Monthly  QuotaUsage
+}
+
+// RetryAfter returns the duration until the exceeded period resets. 0 if allowed
+func (d QuotaDecision) RetryAfter(now time.Time) time.Duration {
+	switch d.Exceeded {
+	case QuotaDaily:
+		return max(d.Daily.Reset.Sub(now), 0)
+	case QuotaMonthly:
+		return max(d.Monthly.Reset.Sub(now), 0)
+	}
+	return 0
+}
+
+// QuotaStore keeps the daily and monthly quota counters of client apps in the KV DB, shared by all instances.
+// The periods follow the calendar of Location.
+type QuotaStore struct {
+	client    kvdb.Client
+	keyPrefix string
+	location  *time.Location
+}
+
+// NewQuotaStore creates a QuotaStore
+// keyPrefix defaults to DefaultQuotaKeyPrefix and location to UTC
+func NewQuotaStore(client kvdb.Client, keyPrefix string, location *time.Location) *QuotaStore {
+	if keyPrefix == "" {
+		keyPrefix = DefaultQuotaKeyPrefix
+	}
+	if location == nil {
+		location = time.UTC
+	}
+	return &QuotaStore{client: client, keyPrefix: keyPrefix, location: location}
+}
+
+// periods returns the keys and the usages (without Used) of the current day and month
+func (s *QuotaStore) periods(clientID string, dailyLimit int64, monthlyLimit int64, now time.Time) (keys []string, daily QuotaUsage, monthly QuotaUsage) {
+	local := now.In(s.location)
+	y, m, d := local.Date()
+	dayStart := time.Date(y, m, d, 0, 0, 0, 0, s.location)
+	monthStart := time.Date(y, m, 1, 0, 0, 0, 0, s.location)
+	keys = []string{
+		s.keyPrefix + clientID + ":d:" + dayStart.Format("20060102"),
+		s.keyPrefix + clientID + ":m:" + monthStart.Format("200601"),
+	}
+	daily = QuotaUsage{Period: QuotaDaily, Limit: dailyLimit, Reset: dayStart.AddDate(0, 0, 1)}
+	monthly = QuotaUsage{Period: QuotaMonthly, Limit: monthlyLimit, Reset: monthStart.AddDate(0, 1, 0)}
+	return keys, daily, monthly
+}
+
+// Consume counts cost against the daily and monthly limits (0 = unlimited) of the client.
+// Nothing is counted if either limit would be exceeded.
+func (s *QuotaStore) Consume(ctx context.Context, clientID string, cost int64, dailyLimit int64, monthlyLimit int64, now time.Time) (QuotaDecision, error) {
+	keys, daily, monthly := s.periods(clientID, dailyLimit, monthlyLimit, now)
+	d := QuotaDecision{Daily: daily, Monthly: monthly}
+	res, err := s.client.Eval(ctx, kvQuotaScript, keys,
+		max(cost, 1), dailyLimit, monthlyLimit,
+		(daily.Reset.Sub(now) + quotaGrace).Milliseconds(),
+		(monthly.Reset.Sub(now) + quotaGrace).Milliseconds(),
+	)
+	if err != nil {
+		return d, err
+	}
+	reply, ok := res.([]any)
+	if !ok || len(reply) != 4 {
+		return d, fmt.Errorf("unexpected script reply: %v", res)
+	}
+	nums := make([]int64, len(reply))
+	for i, v := range reply {
+		if nums[i], ok = v.(int64); !ok {
+			return d, fmt.Errorf("unexpected script reply: %v", res)
+		}
+	}
+	d.Allowed = nums[0] == 1
+	switch nums[1] {
+	case 1:
+		d.Exceeded = QuotaDaily
+	case 2:
+		d.Exceeded = QuotaMonthly
+	}
+	d.Daily.Used = nums[2]
+	d.Monthly.Used = nums[3]
+	return d, nil
+}
+
+// Usage reads the counters of the client without counting. Allowed reports whether both are under their limits
+func (s *QuotaStore) Usage(ctx context.Context, clientID string, dailyLimit int64, monthlyLimit int64, now time.Time) (QuotaDecision, error) {
+	keys, daily, monthly := s.periods(clientID, dailyLimit, monthlyLimit, now)
+	d := QuotaDecision{Daily: daily, Monthly: monthly}
+	var err error
+	if d.Daily.Used, err = s.getCount(ctx, keys[0]); err != nil {
+		return d, err
+	}
+	if d.Monthly.Used, err = s.getCount(ctx, keys[1]); err != nil {
+		return d, err
+	}
+	switch {
+	case dailyLimit > 0 && d.Daily.Used >= dailyLimit:
+		d.Exceeded = QuotaDaily
+	case monthlyLimit > 0 && d.Monthly.Used >= monthlyLimit:
+		d.Exceeded = QuotaMonthly
+	default:
+		d.Allowed = true
+	}
+	return d, nil
+}
+
+func (s *QuotaStore) getCount(ctx context.Context, key string) (int64, error) {
+	val, found, err := s.client.Get(ctx, key)
+	if err != nil || !found {
+		return 0, err
+	}
+	return strconv.ParseInt(val, 10, 64)
+}
+
+// Reset clears the counter of the current period of the client. period = "" resets both
+func (s *QuotaStore) Reset(ctx context.Context, clientID string, period string, now time.Time) error {
+	keys, _, _ := s.periods(clientID, 0, 0, now)
+	switch period {
+	case "":
+	case QuotaDaily:
+		keys = keys[:1]
+	case QuotaMonthly:
+		keys = keys[1:]
+	default:
+		return fmt.Errorf("unknown quota period: %q", period)
+	}
+	_, err := s.client.Delete(ctx, keys...)
+	return err
+}
@@ -0,0 +1,224 @@
+package throttle
+
+import (
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/logitools/gw/db/kvdb"
+)
+
+const DefaultSnapshotTimeout = 5 * time.Second // to save or load a snapshot
+
+// BucketSnapshot is the state of a bucket id in a group. Only the fields of its algorithm are set.
+type BucketSnapshot struct {
+	Group       string      `json:"group"`
+	ID          string      `json:"id"`
+	Algorithm   string      `json:"algorithm"`
+	LastUsed    time.Time   `json:"last_used"`             // [token_bucket] last refill
+	Tokens      int         `json:"tokens,omitzero"`       // [token_bucket]
+	TAT         time.Time   `json:"tat,omitzero"`          // [gcra] theoretical arrival time
+	Times       []time.Time `json:"times,omitempty"`       // [sliding_log]
+	WindowStart time.Time   `json:"window_start,omitzero"` // [sliding_window]
+	Prev        int         `json:"prev,omitzero"`         // [sliding_window]
+	Curr        int         `json:"curr,omitzero"`         // [sliding_window]
+	InFlight    int         `json:"in_flight,omitzero"`    // [concurrency] inspection only. never restored
+}
+
+// Snapshot is the local state of a BucketStore, saved on stop and restored on start
+type Snapshot struct {
+	TakenAt time.Time        `json:"taken_at"`
+	Buckets []BucketSnapshot `json:"buckets"`
+	Bans    []Ban            `json:"bans"`
+}
+
+// SnapshotStorage persists the snapshot of a BucketStore
+type SnapshotStorage interface {
+	SaveSnapshot(ctx context.Context, data []byte) error
+	LoadSnapshot(ctx context.Context) (data []byte, found bool, err error)
+}
+
+// FileSnapshotStorage keeps the snapshot in a local file. Per node
+type FileSnapshotStorage struct {
+	Path string
+}
+
+func (f *FileSnapshotStorage) SaveSnapshot(_ context.Context, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+		return err
+	}
+	tmpPath := f.Path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, f.Path) // no half-written snapshot on a crash
+}
+
+func (f *FileSnapshotStorage) LoadSnapshot(_ context.Context) ([]byte, bool, error) {
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	return data, err == nil, err
+}
+
+// KVSnapshotStorage keeps the snapshot in the KV DB under Key. Use a key per node
+type KVSnapshotStorage struct {
+	Client kvdb.Client
+	Key    string
+	TTL    time.Duration // 0 = no expiration
+}
+
+func (k *KVSnapshotStorage) SaveSnapshot(ctx context.Context, data []byte) error {
+	return k.Client.Set(ctx, k.Key, string(data), k.TTL)
+}
+
+func (k *KVSnapshotStorage) LoadSnapshot(ctx context.Context) ([]byte, bool, error) {
+	val, found, err := k.Client.Get(ctx, k.Key)
+	return []byte(val), found, err
+}
+
+// SetSnapshotStorage saves the local buckets on stop and restores them on start,
+// so that restarting a node does not reset the throttling. Call before Start
+func (s *BucketStore) SetSnapshotStorage(storage SnapshotStorage) {
+	s.snapshotStorage = storage
+}
+
+// Snapshot returns the local state of the buckets and the bans.
+// Concurrency groups are left out since their in-flight requests do not survive a restart.
+func (s *BucketStore) Snapshot(now time.Time) *Snapshot {
+	snap := &Snapshot{TakenAt: now, Buckets: []BucketSnapshot{}, Bans: s.Bans(now)}
+	for groupID, g := range s.bucketGroups() {
+		if g.conf.algorithm() == AlgoConcurrency {
+			continue
+		}
+		g.buckets.Range(func(id, value any) bool {
+			b := value.(limiterState).snapshot()
+			b.Group = groupID
+			b.ID = id.(string)
+			snap.Buckets = append(snap.Buckets, b)
+			return true
+		})
+	}
+	return snap
+}
+
+// Restore loads the buckets and bans of snap, returning the number of buckets restored.
+// Buckets unused for cleanupOlderThan, of unknown groups or of groups whose algorithm changed are discarded.
+func (s *BucketStore) Restore(snap *Snapshot, now time.Time) int {
+	groups := s.bucketGroups()
+	restored := 0
+	for _, b := range snap.Buckets {
+		g, ok := groups[b.Group]
+		if !ok || g.conf.algorithm() != b.Algorithm || now.Sub(b.LastUsed) > s.cleanupOlderThan {
+			continue
+		}
+		state, ok := restoreLimiterState(g, b)
+		if !ok {
+			continue
+		}
+		g.buckets.Store(b.ID, state)
+		restored++
+	}
+	for _, ban := range snap.Bans {
+		if ban.Until.After(now) {
+			s.Ban(ban.Group, ban.ID, ban.Until)
+		}
+	}
+	return restored
+}
+
+// loadSnapshot restores the snapshot from the storage if any. Errors are logged, starting with empty buckets
+func (s *BucketStore) loadSnapshot() {
+	if s.snapshotStorage == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(s.Ctx, DefaultSnapshotTimeout)
+	defer cancel()
+	data, found, err := s.snapshotStorage.LoadSnapshot(ctx)
+	if err != nil {
+		log.Printf("[ERROR][Throttle] failed to load the bucket snapshot: %v", err)
+		return
+	}
+	if !found {
+		return
+	}
+	var snap Snapshot
+	if err = json.Unmarshal(data, &snap); err != nil {
+		log.Printf("[ERROR][Throttle] failed to parse the bucket snapshot: %v", err)
+		return
+	}
+	restored := s.Restore(&snap, time.Now())
+	log.Printf("[INFO][Throttle] %d of %d buckets restored from the snapshot taken at %v", restored, len(snap.Buckets), snap.TakenAt)
+}
+
+// saveSnapshot saves the snapshot to the storage if any. Runs after the service ctx is canceled
+func (s *BucketStore) saveSnapshot() {
+	if s.snapshotStorage == nil {
+		return
+	}
+	snap := s.Snapshot(time.Now())
+	data, err := json.Marshal(snap)
+	if err != nil {
+		log.Printf("[ERROR][Throttle] failed to encode the bucket snapshot: %v", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(s.Ctx), DefaultSnapshotTimeout)
+	defer cancel()
+	if err = s.snapshotStorage.SaveSnapshot(ctx, data); err != nil {
+		log.Printf("[ERROR][Throttle] failed to save the bucket snapshot: %v", err)
+		return
+	}
+	log.Printf("[INFO][Throttle] %d buckets saved to the snapshot", len(snap.Buckets))
+}
+
+// restoreLimiterState builds the state of g's algorithm from b
+func restoreLimiterState(g *BucketGroup, b BucketSnapshot) (limiterState, bool) {
+	conf := g.conf
+	switch conf.algorithm() {
+	case AlgoTokenBucket:
+		return &Bucket{tokens: min(max(b.Tokens, 0), conf.Burst), lastCheck: b.LastUsed, parentGroup: g}, true
+	case AlgoGCRA:
+		return &gcraState{conf: conf, tat: b.TAT, seen: b.LastUsed}, true
+	case AlgoSlidingLog:
+		return &slidingLogState{conf: conf, times: b.Times, seen: b.LastUsed}, true
+	case AlgoSlidingWindow:
+		return &slidingWindowState{conf: conf, start: b.WindowStart, prev: b.Prev, curr: b.Curr, seen: b.LastUsed}, true
+	}
+	return nil, false
+}
+
+func (b *Bucket) snapshot() BucketSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BucketSnapshot{Algorithm: AlgoTokenBucket, LastUsed: b.lastCheck, Tokens: b.tokens}
+}
+
+func (s *gcraState) snapshot() BucketSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return BucketSnapshot{Algorithm: AlgoGCRA, LastUsed: s.seen, TAT: s.tat}
+}
+
+func (s *slidingLogState) snapshot() BucketSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return BucketSnapshot{Algorithm: AlgoSlidingLog, LastUsed: s.seen, Times: append([]time.Time(nil), s.times...)}
+}
+
+func (s *slidingWindowState) snapshot() BucketSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return BucketSnapshot{Algorithm: AlgoSlidingWindow, LastUsed: s.seen, WindowStart: s.start, Prev: s.prev, Curr: s.curr}
+}
+
+func (s *concurrencyState) snapshot() BucketSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return BucketSnapshot{Algorithm: AlgoConcurrency, LastUsed: s.seen, InFlight: s.inFlight}
+}
@@ -1,6 +1,7 @@
 package cmdhandlers
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
@@ -39,7 +40,7 @@ func (h *JobCronLogEvery5Min) HandleCommand(args []string, w io.Writer) error {
 	jobID := "log-msg-every-5min"
 	cronjob := schedjobs.NewEveryMinEmptyCronJob(jobID)
 	cronjob.Minutes = schedjobs.BitsFromMinutes([]int{5, 10, 15, 20, 25, 30, 35, 40, 45, 50, 55})
-	cronjob.Task = func() error {
+	cronjob.Task = func(_ context.Context) error {
 		log.Printf("[CRON] message: %s", msg)
 		return nil
 	}
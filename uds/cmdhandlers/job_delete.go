@@ -0,0 +1,39 @@
+package cmdhandlers
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/logitools/gw/framework"
+)
+
+type JobDelete struct {
+	AppProvider framework.AppProviderFunc
+}
+
+func (*JobDelete) GroupName() string {
+	return "job"
+}
+
+func (h *JobDelete) Command() string {
+	return "job-delete"
+}
+
+func (h *JobDelete) Desc() string {
+	return "Delete the pending one-time jobs of the ID, also from the job store"
+}
+
+func (h *JobDelete) Usage() string {
+	return h.Command() + " jobid"
+}
+
+func (h *JobDelete) HandleCommand(args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %s", h.Usage())
+	}
+	if !h.AppProvider().AppCore().JobScheduler.DeleteOneTimeJob(args[0]) {
+		return fmt.Errorf("one-time job %q not found", args[0])
+	}
+	_, _ = fmt.Fprintf(w, "one-time job %q deleted\n", args[0])
+	return nil
+}
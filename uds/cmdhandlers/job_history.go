@@ -0,0 +1,73 @@
+package cmdhandlers
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/logitools/gw/framework"
+	"github.com/logitools/gw/schedjobs"
+)
+
+type JobHistory struct {
+	AppProvider framework.AppProviderFunc
+}
+
+func (*JobHistory) GroupName() string {
+	return "job"
+}
+
+func (h *JobHistory) Command() string {
+	return "job-history"
+}
+
+func (h *JobHistory) Desc() string {
+	return "Print the recent runs of a job, newest first"
+}
+
+func (h *JobHistory) Usage() string {
+	return h.Command() + " jobid [count]"
+}
+
+// HandleCommandData returns the runs newest first
+func (h *JobHistory) HandleCommandData(args []string) (any, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf("usage: %s", h.Usage())
+	}
+	count := 0 // all
+	if len(args) == 2 {
+		var err error
+		if count, err = strconv.Atoi(args[1]); err != nil || count <= 0 {
+			return nil, fmt.Errorf("usage: %s", h.Usage())
+		}
+	}
+	runs := h.AppProvider().AppCore().JobScheduler.History(args[0])
+	slices.Reverse(runs)
+	if count > 0 && len(runs) > count {
+		runs = runs[:count]
+	}
+	return runs, nil
+}
+
+func (h *JobHistory) HandleCommand(args []string, w io.Writer) error {
+	result, err := h.HandleCommandData(args)
+	if err != nil {
+		return err
+	}
+	runs := result.([]schedjobs.RunRecord)
+	if len(runs) == 0 {
+		_, _ = fmt.Fprintf(w, "no runs of job %q\n", args[0])
+		return nil
+	}
+	for _, rec := range runs {
+		result := "ok"
+		if rec.Err != "" {
+			result = "error: " + rec.Err
+		}
+		_, _ = fmt.Fprintf(w, "%s - %s (%v) attempts=%d %s\n",
+			rec.Start.Format(time.RFC3339), rec.End.Format(time.RFC3339), rec.Duration().Round(time.Millisecond), rec.Attempts, result)
+	}
+	return nil
+}
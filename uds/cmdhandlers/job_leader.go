@@ -0,0 +1,63 @@
+package cmdhandlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/logitools/gw/framework"
+	"github.com/logitools/gw/schedjobs"
+)
+
+type JobLeader struct {
+	AppProvider framework.AppProviderFunc
+}
+
+func (*JobLeader) GroupName() string {
+	return "job"
+}
+
+func (h *JobLeader) Command() string {
+	return "job-leader"
+}
+
+func (h *JobLeader) Desc() string {
+	return "Show the lease mode and the current leader of the job scheduler"
+}
+
+func (h *JobLeader) Usage() string {
+	return h.Command()
+}
+
+func (h *JobLeader) HandleCommandData(args []string) (any, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("usage: %s", h.Usage())
+	}
+	appCore := h.AppProvider().AppCore()
+	ctx, cancel := context.WithTimeout(appCore.RootCtx, 2*time.Second)
+	defer cancel()
+	return appCore.JobScheduler.Leader(ctx)
+}
+
+func (h *JobLeader) HandleCommand(args []string, w io.Writer) error {
+	result, err := h.HandleCommandData(args)
+	if err != nil {
+		return err
+	}
+	info := result.(schedjobs.LeaderInfo)
+	if info.Mode == "" {
+		_, _ = fmt.Fprintln(w, "no lease. every instance runs every job")
+		return nil
+	}
+	_, _ = fmt.Fprintf(w, "mode: %s\nnode: %s\n", info.Mode, info.Node)
+	if info.Mode != schedjobs.LeaseLeader {
+		return nil
+	}
+	if info.Leader == "" {
+		_, _ = fmt.Fprintln(w, "leader: (none)")
+		return nil
+	}
+	_, _ = fmt.Fprintf(w, "leader: %s (token %d, this node: %t)\n", info.Leader, info.Token, info.IsLeader)
+	return nil
+}
@@ -0,0 +1,125 @@
+package cmdhandlers
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/logitools/gw/framework"
+	"github.com/logitools/gw/schedjobs"
+)
+
+type JobList struct {
+	AppProvider framework.AppProviderFunc
+}
+
+type jobListData struct {
+	CronJobs    []cronJobView    `json:"cron_jobs"`
+	OneTimeJobs []oneTimeJobView `json:"one_time_jobs"`
+}
+
+type cronJobView struct {
+	ID      string               `json:"id"`
+	Spec    string               `json:"spec"`
+	Paused  bool                 `json:"paused"`
+	NextRun time.Time            `json:"next_run,omitzero"`
+	LastRun *schedjobs.RunRecord `json:"last_run,omitzero"`
+}
+
+type oneTimeJobView struct {
+	ID       string    `json:"id"`
+	ExecTime time.Time `json:"exec_time"`
+	Type     string    `json:"type,omitzero"`
+}
+
+func (*JobList) GroupName() string {
+	return "job"
+}
+
+func (h *JobList) Command() string {
+	return "job-list"
+}
+
+func (h *JobList) Desc() string {
+	return "List the cron jobs with their next and last runs, and the pending one-time jobs"
+}
+
+func (h *JobList) Usage() string {
+	return h.Command()
+}
+
+func (h *JobList) HandleCommandData(args []string) (any, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("usage: %s", h.Usage())
+	}
+	scheduler := h.AppProvider().AppCore().JobScheduler
+	data := &jobListData{CronJobs: []cronJobView{}, OneTimeJobs: []oneTimeJobView{}}
+	for id, job := range scheduler.GetCronJobs() {
+		view := cronJobView{ID: id, Spec: cronSpec(job), Paused: scheduler.IsPaused(id)}
+		view.NextRun, _ = scheduler.NextRun(id)
+		if rec, ok := scheduler.LastRun(id); ok {
+			view.LastRun = &rec
+		}
+		data.CronJobs = append(data.CronJobs, view)
+	}
+	sort.Slice(data.CronJobs, func(i, j int) bool { return data.CronJobs[i].ID < data.CronJobs[j].ID })
+	for slot, jobs := range scheduler.GetOneTimeJobs() {
+		for _, job := range jobs {
+			data.OneTimeJobs = append(data.OneTimeJobs, oneTimeJobView{ID: job.ID, ExecTime: time.Unix(slot, 0), Type: job.Type})
+		}
+	}
+	sort.Slice(data.OneTimeJobs, func(i, j int) bool {
+		return data.OneTimeJobs[i].ExecTime.Before(data.OneTimeJobs[j].ExecTime)
+	})
+	return data, nil
+}
+
+func (h *JobList) HandleCommand(args []string, w io.Writer) error {
+	result, err := h.HandleCommandData(args)
+	if err != nil {
+		return err
+	}
+	data := result.(*jobListData)
+	_, _ = fmt.Fprintf(w, "cron jobs: %d\n", len(data.CronJobs))
+	for _, job := range data.CronJobs {
+		state := "active"
+		if job.Paused {
+			state = "paused"
+		}
+		next := "-"
+		if !job.NextRun.IsZero() {
+			next = job.NextRun.Format(time.RFC3339)
+		}
+		last := "-"
+		if job.LastRun != nil {
+			result := "ok"
+			if job.LastRun.Err != "" {
+				result = "error"
+			}
+			last = fmt.Sprintf("%s(%s)", job.LastRun.Start.Format(time.RFC3339), result)
+		}
+		_, _ = fmt.Fprintf(w, "  %s [%s] spec=%q next=%s last=%s\n", job.ID, state, job.Spec, next, last)
+	}
+	_, _ = fmt.Fprintf(w, "one-time jobs: %d\n", len(data.OneTimeJobs))
+	for _, job := range data.OneTimeJobs {
+		typ := "-"
+		if job.Type != "" {
+			typ = job.Type
+		}
+		_, _ = fmt.Fprintf(w, "  %s exec=%s type=%s\n", job.ID, job.ExecTime.Format(time.RFC3339), typ)
+	}
+	return nil
+}
+
+// cronSpec describes the schedule of a cron job built with or without ParseCron
+func cronSpec(job *schedjobs.CronJob) string {
+	switch {
+	case job.Spec != "":
+		return job.Spec
+	case job.Every > 0:
+		return "@every " + job.Every.String()
+	default:
+		return "(fields)"
+	}
+}
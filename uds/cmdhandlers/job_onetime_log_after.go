@@ -1,6 +1,7 @@
 package cmdhandlers
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
@@ -47,7 +48,7 @@ func (h *JobOnetimeLogAfter) HandleCommand(args []string, w io.Writer) error {
 	job := &schedjobs.OneTimeJob{
 		ID:       jobID,
 		ExecTime: time.Now().Add(time.Duration(delayInMinutes) * time.Minute),
-		Task: func() error {
+		Task: func(_ context.Context) error {
 			log.Printf("[JOB] message: %s", msg)
 			return nil
 		},
@@ -0,0 +1,39 @@
+package cmdhandlers
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/logitools/gw/framework"
+)
+
+type JobPause struct {
+	AppProvider framework.AppProviderFunc
+}
+
+func (*JobPause) GroupName() string {
+	return "job"
+}
+
+func (h *JobPause) Command() string {
+	return "job-pause"
+}
+
+func (h *JobPause) Desc() string {
+	return "Pause the scheduled runs of a cron job"
+}
+
+func (h *JobPause) Usage() string {
+	return h.Command() + " jobid"
+}
+
+func (h *JobPause) HandleCommand(args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %s", h.Usage())
+	}
+	if err := h.AppProvider().AppCore().JobScheduler.PauseCronJob(args[0]); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "job %q paused\n", args[0])
+	return nil
+}
@@ -0,0 +1,39 @@
+package cmdhandlers
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/logitools/gw/framework"
+)
+
+type JobResume struct {
+	AppProvider framework.AppProviderFunc
+}
+
+func (*JobResume) GroupName() string {
+	return "job"
+}
+
+func (h *JobResume) Command() string {
+	return "job-resume"
+}
+
+func (h *JobResume) Desc() string {
+	return "Resume the scheduled runs of a paused cron job"
+}
+
+func (h *JobResume) Usage() string {
+	return h.Command() + " jobid"
+}
+
+func (h *JobResume) HandleCommand(args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %s", h.Usage())
+	}
+	if err := h.AppProvider().AppCore().JobScheduler.ResumeCronJob(args[0]); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "job %q resumed\n", args[0])
+	return nil
+}
@@ -0,0 +1,39 @@
+package cmdhandlers
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/logitools/gw/framework"
+)
+
+type JobTrigger struct {
+	AppProvider framework.AppProviderFunc
+}
+
+func (*JobTrigger) GroupName() string {
+	return "job"
+}
+
+func (h *JobTrigger) Command() string {
+	return "job-trigger"
+}
+
+func (h *JobTrigger) Desc() string {
+	return "Run a cron job (even if paused) or the pending one-time jobs of the ID now"
+}
+
+func (h *JobTrigger) Usage() string {
+	return h.Command() + " jobid"
+}
+
+func (h *JobTrigger) HandleCommand(args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %s", h.Usage())
+	}
+	if err := h.AppProvider().AppCore().JobScheduler.TriggerJob(args[0]); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "job %q triggered\n", args[0])
+	return nil
+}
@@ -0,0 +1,52 @@
+package cmdhandlers
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/logitools/gw/framework"
+)
+
+type QuotaReset struct {
+	AppProvider framework.AppProviderFunc
+}
+
+func (h *QuotaReset) GroupName() string {
+	return "quota"
+}
+
+func (h *QuotaReset) Command() string {
+	return "quota-reset"
+}
+
+func (h *QuotaReset) Desc() string {
+	return "Reset the current quota counters of the client app"
+}
+
+func (h *QuotaReset) Usage() string {
+	return h.Command() + " clientid [daily|monthly]"
+}
+
+func (h *QuotaReset) HandleCommand(args []string, w io.Writer) error {
+	argLen := len(args)
+	if argLen < 1 || argLen > 2 {
+		return fmt.Errorf("usage: %s", h.Usage())
+	}
+	appCore := h.AppProvider().AppCore()
+	if appCore.ClientQuotaStore == nil {
+		return fmt.Errorf("client quota store not ready")
+	}
+	period := ""
+	if argLen == 2 {
+		period = args[1]
+	}
+	if err := appCore.ClientQuotaStore.Reset(appCore.RootCtx, args[0], period, time.Now()); err != nil {
+		return err
+	}
+	if period == "" {
+		period = "daily and monthly"
+	}
+	_, _ = fmt.Fprintf(w, "quota reset: %s (%s)\n", args[0], period)
+	return nil
+}
@@ -0,0 +1,56 @@
+package cmdhandlers
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/logitools/gw/framework"
+	"github.com/logitools/gw/throttle"
+)
+
+type QuotaShow struct {
+	AppProvider framework.AppProviderFunc
+}
+
+func (h *QuotaShow) GroupName() string {
+	return "quota"
+}
+
+func (h *QuotaShow) Command() string {
+	return "quota-show"
+}
+
+func (h *QuotaShow) Desc() string {
+	return "Print the daily/monthly quota usage of the client app"
+}
+
+func (h *QuotaShow) Usage() string {
+	return h.Command() + " clientid"
+}
+
+func (h *QuotaShow) HandleCommand(args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %s", h.Usage())
+	}
+	appCore := h.AppProvider().AppCore()
+	if appCore.ClientQuotaStore == nil {
+		return fmt.Errorf("client quota store not ready")
+	}
+	clientConf, ok := appCore.GetClientAppConf(args[0])
+	if !ok {
+		return fmt.Errorf("client app not found: %s", args[0])
+	}
+	d, err := appCore.ClientQuotaStore.Usage(appCore.RootCtx, clientConf.ID, clientConf.DailyQuota, clientConf.MonthlyQuota, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, u := range []throttle.QuotaUsage{d.Daily, d.Monthly} {
+		limit := "unlimited"
+		if u.Limit > 0 {
+			limit = fmt.Sprintf("%d", u.Limit)
+		}
+		_, _ = fmt.Fprintf(w, "%s: used=%d limit=%s reset=%s\n", u.Period, u.Used, limit, u.Reset.Format(time.RFC3339))
+	}
+	return nil
+}
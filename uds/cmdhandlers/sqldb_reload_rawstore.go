@@ -0,0 +1,77 @@
+package cmdhandlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/logitools/gw/framework"
+)
+
+type SqldbReloadRawStore struct {
+	AppProvider framework.AppProviderFunc
+}
+
+func (h *SqldbReloadRawStore) GroupName() string {
+	return "sqldb"
+}
+
+func (h *SqldbReloadRawStore) Command() string {
+	return "sql-reload-rawstore"
+}
+
+func (h *SqldbReloadRawStore) Desc() string {
+	return "Hot Reload raw SQL statements from a directory (default: the loaded sql fs). -verify prepares them against the DB"
+}
+
+func (h *SqldbReloadRawStore) Usage() string {
+	return h.Command() + " dbname [dirpath] [-verify]"
+}
+
+func (h *SqldbReloadRawStore) HandleCommand(args []string, w io.Writer) error {
+	var (
+		positional []string
+		verify     bool
+	)
+	for _, arg := range args {
+		if arg == "-verify" {
+			verify = true
+			continue
+		}
+		positional = append(positional, arg)
+	}
+	if len(positional) != 1 && len(positional) != 2 {
+		return fmt.Errorf("usage: %s", h.Usage())
+	}
+	var sqlFS fs.FS // nil: the sql fs given to PrepareSQLDatabases
+	if len(positional) == 2 {
+		info, err := os.Stat(positional[1])
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("not a directory: %s", positional[1])
+		}
+		sqlFS = os.DirFS(positional[1])
+	}
+	appCore := h.AppProvider().AppCore()
+	ctx, cancel := context.WithTimeout(appCore.RootCtx, 30*time.Second)
+	defer cancel()
+	report, err := appCore.ReloadSQLRawStore(ctx, positional[0], sqlFS, verify)
+	if report != nil {
+		for _, key := range report.Shadowed {
+			_, _ = fmt.Fprintf(w, "shadowed: %s (.sql by .%s)\n", key, report.DBType)
+		}
+		for _, key := range report.Skipped {
+			_, _ = fmt.Fprintf(w, "not verified: %s\n", key)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("not reloaded: %w", err)
+	}
+	_, _ = fmt.Fprintf(w, "%d raw SQL statements hot-reloaded\n", report.Loaded)
+	return nil
+}
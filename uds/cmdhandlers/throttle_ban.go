@@ -0,0 +1,71 @@
+package cmdhandlers
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/logitools/gw/framework"
+	"github.com/logitools/gw/throttle"
+)
+
+type ThrottleBan struct {
+	AppProvider framework.AppProviderFunc
+}
+
+func (h *ThrottleBan) GroupName() string {
+	return "throttle"
+}
+
+func (h *ThrottleBan) Command() string {
+	return "throttle-ban"
+}
+
+func (h *ThrottleBan) Desc() string {
+	return "Ban a bucket id in a throttle bucket group (or * for all groups) for a duration. 0 lifts the ban. No args lists the bans"
+}
+
+func (h *ThrottleBan) Usage() string {
+	return h.Command() + " [groupid|* bucketid duration(e.g. 30m)]"
+}
+
+func (h *ThrottleBan) HandleCommand(args []string, w io.Writer) error {
+	appCore := h.AppProvider().AppCore()
+	store := appCore.ThrottleBucketStore
+	if store == nil {
+		return fmt.Errorf("throttle bucket store not ready")
+	}
+	now := time.Now()
+	switch len(args) {
+	case 0:
+		for _, ban := range store.Bans(now) {
+			_, _ = fmt.Fprintf(w, "[%s] %s until %s\n", ban.Group, ban.ID, ban.Until.Format(time.RFC3339))
+		}
+		return nil
+	case 3:
+	default:
+		return fmt.Errorf("usage: %s", h.Usage())
+	}
+	groupID, bucketID := args[0], args[1]
+	dur, err := time.ParseDuration(args[2])
+	if err != nil || dur < 0 {
+		return fmt.Errorf("invalid duration: %s", args[2])
+	}
+	if groupID != throttle.BanAllGroups {
+		if _, ok := store.GetBucketGroup(groupID); !ok {
+			return fmt.Errorf("bucket group not found: %s", groupID)
+		}
+	}
+	if dur == 0 {
+		if !store.Unban(groupID, bucketID) {
+			_, _ = fmt.Fprintln(w, "not banned")
+			return nil
+		}
+		_, _ = fmt.Fprintf(w, "ban lifted: [%s] %s\n", groupID, bucketID)
+		return nil
+	}
+	until := now.Add(dur)
+	store.Ban(groupID, bucketID, until)
+	_, _ = fmt.Fprintf(w, "banned: [%s] %s until %s\n", groupID, bucketID, until.Format(time.RFC3339))
+	return nil
+}
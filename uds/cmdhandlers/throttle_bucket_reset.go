@@ -0,0 +1,49 @@
+package cmdhandlers
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/logitools/gw/framework"
+)
+
+type ThrottleBucketReset struct {
+	AppProvider framework.AppProviderFunc
+}
+
+func (h *ThrottleBucketReset) GroupName() string {
+	return "throttle"
+}
+
+func (h *ThrottleBucketReset) Command() string {
+	return "throttle-bucket-reset"
+}
+
+func (h *ThrottleBucketReset) Desc() string {
+	return "Reset a bucket id in a throttle bucket group to a full quota"
+}
+
+func (h *ThrottleBucketReset) Usage() string {
+	return h.Command() + " groupid bucketid"
+}
+
+func (h *ThrottleBucketReset) HandleCommand(args []string, w io.Writer) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: %s", h.Usage())
+	}
+	appCore := h.AppProvider().AppCore()
+	store := appCore.ThrottleBucketStore
+	if store == nil {
+		return fmt.Errorf("throttle bucket store not ready")
+	}
+	found, err := store.ResetBucket(appCore.RootCtx, args[0], args[1])
+	if err != nil {
+		return err
+	}
+	if !found {
+		_, _ = fmt.Fprintln(w, "no bucket to reset")
+		return nil
+	}
+	_, _ = fmt.Fprintf(w, "bucket reset: [%s] %s\n", args[0], args[1])
+	return nil
+}
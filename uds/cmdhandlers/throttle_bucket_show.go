@@ -0,0 +1,72 @@
+package cmdhandlers
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/logitools/gw/framework"
+	"github.com/logitools/gw/throttle"
+)
+
+type ThrottleBucketShow struct {
+	AppProvider framework.AppProviderFunc
+}
+
+func (h *ThrottleBucketShow) GroupName() string {
+	return "throttle"
+}
+
+func (h *ThrottleBucketShow) Command() string {
+	return "throttle-bucket-show"
+}
+
+func (h *ThrottleBucketShow) Desc() string {
+	return "Print the local state and the ban of a bucket id in a throttle bucket group"
+}
+
+func (h *ThrottleBucketShow) Usage() string {
+	return h.Command() + " groupid bucketid"
+}
+
+func (h *ThrottleBucketShow) HandleCommand(args []string, w io.Writer) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: %s", h.Usage())
+	}
+	groupID, bucketID := args[0], args[1]
+	appCore := h.AppProvider().AppCore()
+	store := appCore.ThrottleBucketStore
+	if store == nil {
+		return fmt.Errorf("throttle bucket store not ready")
+	}
+	g, ok := store.GetBucketGroup(groupID)
+	if !ok {
+		return fmt.Errorf("bucket group not found: %s", groupID)
+	}
+	now := time.Now()
+	if until, banned := store.BannedUntil(groupID, bucketID, now); banned {
+		_, _ = fmt.Fprintf(w, "banned until %s\n", until.Format(time.RFC3339))
+	}
+	b, ok := store.InspectBucket(groupID, bucketID)
+	if !ok {
+		_, _ = fmt.Fprintln(w, "no local bucket")
+		return nil
+	}
+	_, _ = fmt.Fprintf(w, "algorithm: %s\nlast used: %s\n", b.Algorithm, b.LastUsed.Format(time.RFC3339Nano))
+	switch b.Algorithm {
+	case throttle.AlgoTokenBucket:
+		_, _ = fmt.Fprintf(w, "tokens: %d/%d\n", b.Tokens, g.Conf().Burst)
+	case throttle.AlgoGCRA:
+		_, _ = fmt.Fprintf(w, "tat: %s\n", b.TAT.Format(time.RFC3339Nano))
+	case throttle.AlgoSlidingLog:
+		_, _ = fmt.Fprintf(w, "requests in log: %d/%d\n", len(b.Times), g.Conf().Limit)
+	case throttle.AlgoSlidingWindow:
+		_, _ = fmt.Fprintf(w, "window start: %s\nprev: %d\ncurr: %d\n", b.WindowStart.Format(time.RFC3339Nano), b.Prev, b.Curr)
+	case throttle.AlgoConcurrency:
+		_, _ = fmt.Fprintf(w, "in flight: %d/%d\n", b.InFlight, g.Conf().Limit)
+	}
+	if g.Conf().Distributed {
+		_, _ = fmt.Fprintln(w, "distributed: the local bucket is only used while the KV DB is unavailable")
+	}
+	return nil
+}
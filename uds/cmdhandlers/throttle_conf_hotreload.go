@@ -0,0 +1,41 @@
+package cmdhandlers
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/logitools/gw/framework"
+)
+
+type ThrottleConfHotReload struct {
+	AppProvider framework.AppProviderFunc
+}
+
+func (h *ThrottleConfHotReload) GroupName() string {
+	return "throttle"
+}
+
+func (h *ThrottleConfHotReload) Command() string {
+	return "throttle-conf-hotreload"
+}
+
+func (h *ThrottleConfHotReload) Desc() string {
+	return "Hot Reload throttle bucket groups and route policies"
+}
+
+func (h *ThrottleConfHotReload) Usage() string {
+	return h.Command()
+}
+
+func (h *ThrottleConfHotReload) HandleCommand(_ []string, w io.Writer) error {
+	appCore := h.AppProvider().AppCore()
+	if appCore.ThrottleBucketStore == nil {
+		return fmt.Errorf("throttle bucket store not ready")
+	}
+	if err := appCore.ReloadThrottleConf(); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "throttle conf hot-reloaded: %d groups, %d route policies\n",
+		len(appCore.ThrottleBucketStore.BucketConfs()), len(appCore.ThrottleBucketStore.RoutePolicies()))
+	return nil
+}
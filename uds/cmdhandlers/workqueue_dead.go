@@ -0,0 +1,66 @@
+package cmdhandlers
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/logitools/gw/framework"
+	"github.com/logitools/gw/workqueue"
+)
+
+type WorkQueueDead struct {
+	AppProvider framework.AppProviderFunc
+}
+
+func (*WorkQueueDead) GroupName() string {
+	return "workqueue"
+}
+
+func (h *WorkQueueDead) Command() string {
+	return "workqueue-dead"
+}
+
+func (h *WorkQueueDead) Desc() string {
+	return "List the dead-lettered tasks, oldest first. Default count: 20"
+}
+
+func (h *WorkQueueDead) Usage() string {
+	return h.Command() + " [count]"
+}
+
+func (h *WorkQueueDead) HandleCommandData(args []string) (any, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("usage: %s", h.Usage())
+	}
+	count := 20
+	if len(args) == 1 {
+		var err error
+		if count, err = strconv.Atoi(args[0]); err != nil || count <= 0 {
+			return nil, fmt.Errorf("usage: %s", h.Usage())
+		}
+	}
+	appCore := h.AppProvider().AppCore()
+	if appCore.WorkQueue == nil {
+		return nil, fmt.Errorf("work queue not ready")
+	}
+	return appCore.WorkQueue.DeadTasks(appCore.RootCtx, count)
+}
+
+func (h *WorkQueueDead) HandleCommand(args []string, w io.Writer) error {
+	result, err := h.HandleCommandData(args)
+	if err != nil {
+		return err
+	}
+	tasks := result.([]*workqueue.Task)
+	if len(tasks) == 0 {
+		_, _ = fmt.Fprintln(w, "no dead-lettered tasks")
+		return nil
+	}
+	for _, task := range tasks {
+		_, _ = fmt.Fprintf(w, "%s type=%s attempts=%d created=%s error=%q payload=%s\n",
+			task.ID, task.Type, task.Attempts, task.CreatedAt.Format(time.RFC3339), task.LastError, task.Payload)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package cmdhandlers
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/logitools/gw/framework"
+)
+
+type WorkQueueDeadPurge struct {
+	AppProvider framework.AppProviderFunc
+}
+
+func (*WorkQueueDeadPurge) GroupName() string {
+	return "workqueue"
+}
+
+func (h *WorkQueueDeadPurge) Command() string {
+	return "workqueue-dead-purge"
+}
+
+func (h *WorkQueueDeadPurge) Desc() string {
+	return "Delete all the dead-lettered tasks"
+}
+
+func (h *WorkQueueDeadPurge) Usage() string {
+	return h.Command()
+}
+
+func (h *WorkQueueDeadPurge) HandleCommand(args []string, w io.Writer) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: %s", h.Usage())
+	}
+	appCore := h.AppProvider().AppCore()
+	if appCore.WorkQueue == nil {
+		return fmt.Errorf("work queue not ready")
+	}
+	n, err := appCore.WorkQueue.PurgeDead(appCore.RootCtx)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "%d dead-lettered tasks purged\n", n)
+	return nil
+}
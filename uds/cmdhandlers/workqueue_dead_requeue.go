@@ -0,0 +1,50 @@
+package cmdhandlers
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/logitools/gw/framework"
+)
+
+type WorkQueueDeadRequeue struct {
+	AppProvider framework.AppProviderFunc
+}
+
+func (*WorkQueueDeadRequeue) GroupName() string {
+	return "workqueue"
+}
+
+func (h *WorkQueueDeadRequeue) Command() string {
+	return "workqueue-dead-requeue"
+}
+
+func (h *WorkQueueDeadRequeue) Desc() string {
+	return "Move dead-lettered tasks back to the ready list with their attempts reset"
+}
+
+func (h *WorkQueueDeadRequeue) Usage() string {
+	return h.Command() + " taskid [taskid ...]"
+}
+
+func (h *WorkQueueDeadRequeue) HandleCommand(args []string, w io.Writer) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: %s", h.Usage())
+	}
+	appCore := h.AppProvider().AppCore()
+	if appCore.WorkQueue == nil {
+		return fmt.Errorf("work queue not ready")
+	}
+	for _, id := range args {
+		requeued, err := appCore.WorkQueue.RequeueDead(appCore.RootCtx, id)
+		switch {
+		case err != nil:
+			_, _ = fmt.Fprintf(w, "%s: error: %v\n", id, err)
+		case !requeued:
+			_, _ = fmt.Fprintf(w, "%s: not dead-lettered\n", id)
+		default:
+			_, _ = fmt.Fprintf(w, "%s: requeued\n", id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package cmdhandlers
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/logitools/gw/framework"
+	"github.com/logitools/gw/workqueue"
+)
+
+type WorkQueueStats struct {
+	AppProvider framework.AppProviderFunc
+}
+
+func (*WorkQueueStats) GroupName() string {
+	return "workqueue"
+}
+
+func (h *WorkQueueStats) Command() string {
+	return "workqueue-stats"
+}
+
+func (h *WorkQueueStats) Desc() string {
+	return "Print the numbers of ready, processing, delayed and dead-lettered tasks"
+}
+
+func (h *WorkQueueStats) Usage() string {
+	return h.Command()
+}
+
+func (h *WorkQueueStats) HandleCommandData(args []string) (any, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("usage: %s", h.Usage())
+	}
+	appCore := h.AppProvider().AppCore()
+	if appCore.WorkQueue == nil {
+		return nil, fmt.Errorf("work queue not ready")
+	}
+	return appCore.WorkQueue.Stats(appCore.RootCtx)
+}
+
+func (h *WorkQueueStats) HandleCommand(args []string, w io.Writer) error {
+	result, err := h.HandleCommandData(args)
+	if err != nil {
+		return err
+	}
+	stats := result.(workqueue.Stats)
+	_, _ = fmt.Fprintf(w, "queue: %s\nready: %d\nprocessing: %d\ndelayed: %d\ndead: %d\n",
+		h.AppProvider().AppCore().WorkQueue.Conf().Queue, stats.Ready, stats.Processing, stats.Delayed, stats.Dead)
+	return nil
+}
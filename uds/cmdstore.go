@@ -69,6 +69,33 @@ func (s *CommandStore) GetHandler(cmd string) (CommandHandler, bool) {
 	return handler, ok
 }
 
+// CommandInfo describes a command for the help of the JSON mode
+type CommandInfo struct {
+	Group   string `json:"group"`
+	Command string `json:"command"`
+	Desc    string `json:"desc"`
+	Usage   string `json:"usage"`
+}
+
+// Commands returns the commands in the display order
+func (s *CommandStore) Commands() []CommandInfo {
+	var infos []CommandInfo
+	for _, grpName := range s.groupDisplayOrder {
+		cmdGrp, ok := s.groupMap[grpName]
+		if !ok {
+			continue
+		}
+		for _, cmd := range cmdGrp.displayOrder {
+			cmdHandler, ok := cmdGrp.handlerMap[cmd]
+			if !ok {
+				continue
+			}
+			infos = append(infos, CommandInfo{Group: grpName, Command: cmd, Desc: cmdHandler.Desc(), Usage: cmdHandler.Usage()})
+		}
+	}
+	return infos
+}
+
 func (s *CommandStore) PrintHelp(w io.Writer) {
 	_, _ = fmt.Fprintln(w)
 	for _, grpName := range s.groupDisplayOrder {
@@ -0,0 +1,113 @@
+package uds
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+)
+
+// JSONModeCommand switches a connection to the JSON mode: one Request per line, answered by one Response per line, without prompts.
+// Send it at the first "> " prompt. The switch is acknowledged by a Response with data {"mode": "json"}
+const JSONModeCommand = "json"
+
+// Response Statuses
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// Request is a line of the JSON mode
+type Request struct {
+	ID   string   `json:"id"` // echoed in the Response
+	Cmd  string   `json:"cmd"`
+	Args []string `json:"args"`
+}
+
+// Response is a line of the JSON mode.
+// Data is the structured result of a StructuredCommandHandler. Output is the text written by the other handlers
+type Response struct {
+	ID     string `json:"id"`
+	Status string `json:"status"` // StatusXXX
+	Data   any    `json:"data,omitzero"`
+	Output string `json:"output,omitzero"`
+	Error  string `json:"error,omitzero"`
+}
+
+// StructuredCommandHandler is a CommandHandler that returns a structured result in the JSON mode
+type StructuredCommandHandler interface {
+	CommandHandler
+	HandleCommandData(args []string) (any, error) // result marshaled to JSON
+}
+
+// serveJSON serves the JSON mode until the client quits or disconnects
+func (s *Service) serveJSON(reader *bufio.Reader, w io.Writer) {
+	writeResponse(w, &Response{Status: StatusOK, Data: map[string]string{"mode": JSONModeCommand}})
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				log.Println("[INFO][UDS] client disconnected")
+			} else {
+				log.Printf("[ERROR][UDS] read error: %v\n", err)
+			}
+			return
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var req Request
+		if err = json.Unmarshal([]byte(line), &req); err != nil {
+			writeResponse(w, &Response{Status: StatusError, Error: fmt.Sprintf("invalid request: %v", err)})
+			continue
+		}
+		if req.Cmd == "q" || req.Cmd == "quit" {
+			writeResponse(w, &Response{ID: req.ID, Status: StatusOK})
+			return
+		}
+		writeResponse(w, s.handleRequest(&req))
+	}
+}
+
+func (s *Service) handleRequest(req *Request) *Response {
+	res := &Response{ID: req.ID, Status: StatusOK}
+	if req.Cmd == "h" || req.Cmd == "help" {
+		res.Data = s.Commands()
+		return res
+	}
+	handler, ok := s.GetHandler(req.Cmd)
+	if !ok {
+		res.Status, res.Error = StatusError, "unknown command: "+req.Cmd
+		return res
+	}
+	cmdLine := strings.Join(append([]string{req.Cmd}, req.Args...), " ")
+	log.Printf("[INFO][UDS] json `%s`\n", cmdLine)
+	var err error
+	if structured, ok := handler.(StructuredCommandHandler); ok {
+		res.Data, err = structured.HandleCommandData(req.Args)
+	} else {
+		var out bytes.Buffer
+		err = handler.HandleCommand(req.Args, &out)
+		res.Output = out.String()
+	}
+	if err != nil {
+		res.Status, res.Error = StatusError, err.Error()
+		log.Printf("[ERROR][UDS] json `%s` terminated: %v\n", cmdLine, err)
+	} else {
+		log.Printf("[INFO][UDS] json `%s` completed\n", cmdLine)
+	}
+	return res
+}
+
+func writeResponse(w io.Writer, res *Response) {
+	data, err := json.Marshal(res)
+	if err != nil {
+		data, _ = json.Marshal(&Response{ID: res.ID, Status: StatusError, Error: fmt.Sprintf("cannot marshal the result: %v", err)})
+	}
+	_, _ = w.Write(append(data, '\n'))
+}
@@ -148,6 +148,11 @@ func (s *Service) handleConn(c net.Conn) {
 			s.CommandStore.PrintHelp(c)
 			continue
 		}
+		if cmdStr == JSONModeCommand {
+			log.Println("[INFO][UDS] switched to the json mode")
+			s.serveJSON(reader, c)
+			return
+		}
 		// look it up in the command map
 		if handler, ok := s.GetHandler(cmdStr); ok {
 			log.Printf("[INFO][UDS] `%s`\n", line)
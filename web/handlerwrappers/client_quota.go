@@ -0,0 +1,61 @@
+package handlerwrappers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/logitools/gw/clients"
+	"github.com/logitools/gw/framework"
+	"github.com/logitools/gw/reason"
+	"github.com/logitools/gw/web/responses"
+)
+
+// ClientQuota counts the request cost against the daily/monthly quotas of the client app
+// prerequisite: ClientAppConf in the Request Context _ e.g. APIClients
+// Fails open when the KV DB is unavailable, or when the ClientQuotaStore is not prepared (PrepareClientQuotas).
+type ClientQuota struct {
+	AppProvider framework.AppProviderFunc
+	Cost        int // Optional. 0 = the cost in the request ctx (throttle.WithCost), or 1
+}
+
+func (m *ClientQuota) Wrap(inner http.Handler) http.Handler {
+	var notPrepared sync.Once // logs once per wrapped handler, not per request
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		clientConf, ok := clients.ClientConfFromContext(ctx)
+		if !ok {
+			responses.WriteSimpleErrorJSON(w, http.StatusUnauthorized, "client app required")
+			return
+		}
+		// At the time of the request, so that routes may be wrapped before PrepareClientQuotas
+		quotaStore := m.AppProvider().AppCore().ClientQuotaStore
+		if quotaStore == nil {
+			notPrepared.Do(func() {
+				log.Println("[ERROR][ClientQuota] ClientQuotaStore not prepared. quotas are not enforced. call PrepareClientQuotas")
+			})
+		} else if clientConf.DailyQuota > 0 || clientConf.MonthlyQuota > 0 {
+			now := time.Now()
+			cost := int64(requestCost(r, m.Cost))
+			decision, err := quotaStore.Consume(ctx, clientConf.ID, cost, clientConf.DailyQuota, clientConf.MonthlyQuota, now)
+			if err != nil {
+				log.Printf("[WARN][ClientQuota] quota check failed for %s, allowed: %v", clientConf.ID, err)
+			} else if !decision.Allowed {
+				w.Header().Set("Retry-After", strconv.FormatInt(max(ceilSeconds(decision.RetryAfter(now)), 1), 10))
+				responses.EncodeWriteJSON(w, http.StatusTooManyRequests, responses.Message{
+					Type:    "error",
+					Message: decision.Exceeded + " quota exceeded",
+					Code:    reason.QuotaExceeded,
+				})
+				return
+			}
+		}
+
+		// Inner
+		inner.ServeHTTP(w, r)
+
+		// Post-action
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/logitools/gw/framework"
+	"github.com/logitools/gw/throttle"
 	"github.com/logitools/gw/web/cookiesession"
 	"github.com/logitools/gw/web/responses"
 )
@@ -12,11 +13,16 @@ import (
 type ThrottleCookieSession struct {
 	AppProvider   framework.AppProviderFunc
 	BucketGroupID string
+	Limiter       throttle.Limiter // Optional. Default: AppCore.ThrottleBucketStore, resolved per request
+	Cost          int              // Optional. Permits per request. 0 = the cost in the request ctx (throttle.WithCost), or 1
 }
 
 func (m *ThrottleCookieSession) Wrap(inner http.Handler) http.Handler {
-	appCore := m.AppProvider().AppCore()
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter, ok := throttleLimiter(w, m.AppProvider, m.Limiter)
+		if !ok {
+			return
+		}
 		ctx := r.Context()
 		// Prerequisite _ SessionID
 		sessionID, ok := cookiesession.SessionIDFromContext(ctx)
@@ -25,8 +31,11 @@ func (m *ThrottleCookieSession) Wrap(inner http.Handler) http.Handler {
 			return
 		}
 		// Check Throttle Bucket
-		if !appCore.ThrottleBucketStore.Allow(m.BucketGroupID, sessionID, time.Now()) {
-			responses.WriteSimpleErrorJSON(w, http.StatusTooManyRequests, "session rate limited")
+		decision, release := limiter.AcquireN(m.BucketGroupID, sessionID, requestCost(r, m.Cost), time.Now())
+		defer release() // after the inner handler completes
+		writeRateLimitHeaders(w, decision)
+		if !decision.Allowed {
+			writeRateLimited(w, decision, "session rate limited")
 			return
 		}
 
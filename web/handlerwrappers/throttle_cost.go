@@ -0,0 +1,28 @@
+package handlerwrappers
+
+import (
+	"net/http"
+
+	"github.com/logitools/gw/throttle"
+)
+
+// ThrottleCost sets the throttle cost of the wrapped route, e.g. 10 for PDF generation.
+// Wrap it outside the throttle wrappers, which take the cost from the request ctx.
+type ThrottleCost struct {
+	Cost int
+}
+
+func (m *ThrottleCost) Wrap(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := throttle.WithCost(r.Context(), m.Cost)
+		inner.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requestCost returns the cost set on the wrapper, or the one in the request ctx
+func requestCost(r *http.Request, cost int) int {
+	if cost > 0 {
+		return cost
+	}
+	return throttle.CostFromContext(r.Context())
+}
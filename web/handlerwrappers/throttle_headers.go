@@ -0,0 +1,57 @@
+package handlerwrappers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/logitools/gw/framework"
+	"github.com/logitools/gw/reason"
+	"github.com/logitools/gw/throttle"
+	"github.com/logitools/gw/web/responses"
+)
+
+// throttleLimiter returns the limiter of a throttle wrapper: its own, else AppCore.ThrottleBucketStore at the time of the request,
+// so that routes may be wrapped before PrepareThrottleBucketStore. Without either, it writes 500 and returns false
+func throttleLimiter(w http.ResponseWriter, appProvider framework.AppProviderFunc, limiter throttle.Limiter) (throttle.Limiter, bool) {
+	if limiter != nil {
+		return limiter, true
+	}
+	if store := appProvider().AppCore().ThrottleBucketStore; store != nil {
+		return store, true
+	}
+	log.Println("[ERROR][Throttle] ThrottleBucketStore not prepared. call PrepareThrottleBucketStore")
+	responses.WriteSimpleErrorJSON(w, http.StatusInternalServerError, "throttle not available")
+	return nil, false
+}
+
+// writeRateLimitHeaders sets the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset (seconds) headers
+// Nothing for an unknown bucket group
+func writeRateLimitHeaders(w http.ResponseWriter, d throttle.Decision) {
+	if d.Limit == 0 {
+		return
+	}
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	h.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(d.Reset), 10))
+}
+
+// writeRateLimited writes 429 with the Retry-After header (at least 1 second) and a reason code
+func writeRateLimited(w http.ResponseWriter, d throttle.Decision, msg string) {
+	w.Header().Set("Retry-After", strconv.FormatInt(max(ceilSeconds(d.RetryAfter), 1), 10))
+	code := reason.RateLimited
+	if d.Algorithm == throttle.AlgoConcurrency && !d.Banned {
+		code = reason.TooManyInFlight
+	}
+	responses.EncodeWriteJSON(w, http.StatusTooManyRequests, responses.Message{
+		Type:    "error",
+		Message: msg,
+		Code:    code,
+	})
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
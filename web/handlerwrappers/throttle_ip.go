@@ -5,23 +5,31 @@ import (
 	"time"
 
 	"github.com/logitools/gw/framework"
+	"github.com/logitools/gw/throttle"
 	"github.com/logitools/gw/web/requests"
-	"github.com/logitools/gw/web/responses"
 )
 
 type ThrottleIP struct {
 	AppProvider   framework.AppProviderFunc
 	BucketGroupID string
+	Limiter       throttle.Limiter // Optional. Default: AppCore.ThrottleBucketStore, resolved per request
+	Cost          int              // Optional. Permits per request. 0 = the cost in the request ctx (throttle.WithCost), or 1
 }
 
 func (m *ThrottleIP) Wrap(inner http.Handler) http.Handler {
-	appCore := m.AppProvider().AppCore()
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter, ok := throttleLimiter(w, m.AppProvider, m.Limiter)
+		if !ok {
+			return
+		}
 		// Requested IP
 		ip := requests.GetClientIP(r)
 		// Check Throttle Bucket
-		if !appCore.ThrottleBucketStore.Allow(m.BucketGroupID, ip, time.Now()) {
-			responses.WriteSimpleErrorJSON(w, http.StatusTooManyRequests, "access rate limited - ip "+ip)
+		decision, release := limiter.AcquireN(m.BucketGroupID, ip, requestCost(r, m.Cost), time.Now())
+		defer release() // after the inner handler completes
+		writeRateLimitHeaders(w, decision)
+		if !decision.Allowed {
+			writeRateLimited(w, decision, "access rate limited - ip "+ip)
 			return
 		}
 
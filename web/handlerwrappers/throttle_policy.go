@@ -0,0 +1,107 @@
+package handlerwrappers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/logitools/gw/clients"
+	"github.com/logitools/gw/framework"
+	"github.com/logitools/gw/throttle"
+	"github.com/logitools/gw/web/cookiesession"
+	"github.com/logitools/gw/web/requests"
+	"github.com/logitools/gw/web/responses"
+)
+
+// ThrottlePolicy applies the bucket groups of the route policies in .throttle.json to the matched route.
+// Wrap the routes (not the ServeMux), so that the request pattern is known.
+// A group whose key is not available for the request (e.g. no user for "user") is skipped.
+type ThrottlePolicy struct {
+	AppProvider    framework.AppProviderFunc
+	UIDStrProvider func(context.Context) (string, error) // Optional. for "user" keys
+}
+
+func (m *ThrottlePolicy) Wrap(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		store := m.AppProvider().AppCore().ThrottleBucketStore
+		if store == nil {
+			log.Println("[ERROR][Throttle] ThrottleBucketStore not prepared. call PrepareThrottleBucketStore")
+			responses.WriteSimpleErrorJSON(w, http.StatusInternalServerError, "throttle not available")
+			return
+		}
+		now := time.Now()
+		var (
+			reported throttle.Decision // the most restrictive allowed decision
+			checked  bool
+			refunds  []func() // of the groups allowed so far. called if a later group denies
+		)
+		groupIDs, cost := store.RouteGroupsCost(r.Pattern)
+		if cost == 0 {
+			cost = throttle.CostFromContext(r.Context())
+		}
+		for _, groupID := range groupIDs {
+			g, ok := store.GetBucketGroup(groupID)
+			if !ok {
+				continue // removed by a reload in between
+			}
+			key := m.requestKey(g.Conf().KeySource, r)
+			if key == "" {
+				continue
+			}
+			decision, release, refund := store.ReserveN(groupID, key, cost, now)
+			defer release() // after the inner handler completes
+			if !decision.Allowed {
+				for _, refund := range refunds {
+					refund()
+				}
+				writeRateLimitHeaders(w, decision)
+				writeRateLimited(w, decision, "rate limited - "+groupID)
+				return
+			}
+			refunds = append(refunds, refund)
+			if !checked || decision.Remaining < reported.Remaining {
+				reported = decision
+				checked = true
+			}
+		}
+		if checked {
+			writeRateLimitHeaders(w, reported)
+		}
+
+		// Inner
+		inner.ServeHTTP(w, r)
+
+		// Post-action
+	})
+}
+
+// requestKey returns the bucket id of the request for the key source. "" if not available
+func (m *ThrottlePolicy) requestKey(keySource string, r *http.Request) string {
+	switch keySource {
+	case throttle.KeyIP:
+		return requests.GetClientIP(r)
+	case throttle.KeyUser:
+		if m.UIDStrProvider == nil {
+			return ""
+		}
+		uidStr, err := m.UIDStrProvider(r.Context())
+		if err != nil {
+			return ""
+		}
+		return uidStr
+	case throttle.KeySession:
+		sessionID, _ := cookiesession.SessionIDFromContext(r.Context())
+		return sessionID
+	case throttle.KeyClientID:
+		if conf, ok := clients.ClientConfFromContext(r.Context()); ok {
+			return conf.ID
+		}
+		return r.Header.Get("Client-Id")
+	}
+	if name, ok := strings.CutPrefix(keySource, throttle.KeyHeaderPrefix); ok {
+		return r.Header.Get(name)
+	}
+	return ""
+}
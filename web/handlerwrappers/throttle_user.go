@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/logitools/gw/framework"
+	"github.com/logitools/gw/throttle"
 	"github.com/logitools/gw/web/responses"
 )
 
@@ -13,13 +14,18 @@ type ThrottleUser struct {
 	AppProvider    framework.AppProviderFunc
 	UIDStrProvider func(context.Context) (string, error)
 	BucketGroupID  string
+	Limiter        throttle.Limiter // Optional. Default: AppCore.ThrottleBucketStore, resolved per request
+	Cost           int              // Optional. Permits per request. 0 = the cost in the request ctx (throttle.WithCost), or 1
 }
 
 // Wrap the middleware func
 // prerequisite: UserID in the Request Context _ e.g. accesstoken.APIAccessTokenSession
 func (m *ThrottleUser) Wrap(inner http.Handler) http.Handler {
-	appCore := m.AppProvider().AppCore()
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter, ok := throttleLimiter(w, m.AppProvider, m.Limiter)
+		if !ok {
+			return
+		}
 		ctx := r.Context()
 
 		// UserID String
@@ -30,8 +36,11 @@ func (m *ThrottleUser) Wrap(inner http.Handler) http.Handler {
 		}
 
 		// Check Throttle Bucket
-		if !appCore.ThrottleBucketStore.Allow(m.BucketGroupID, uidStr, time.Now()) {
-			responses.WriteSimpleErrorJSON(w, http.StatusTooManyRequests, "rate limited")
+		decision, release := limiter.AcquireN(m.BucketGroupID, uidStr, requestCost(r, m.Cost), time.Now())
+		defer release() // after the inner handler completes
+		writeRateLimitHeaders(w, decision)
+		if !decision.Allowed {
+			writeRateLimited(w, decision, "rate limited")
 			return
 		}
 
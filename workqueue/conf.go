@@ -0,0 +1,54 @@
+package workqueue
+
+const (
+	DefaultKeyPrefix         = "workqueue:"
+	DefaultQueue             = "default"
+	DefaultConcurrency       = 4
+	DefaultPollInterval      = 1000    // milliseconds
+	DefaultVisibilityTimeout = 60000   // milliseconds
+	DefaultMaxAttempts       = 5       // then dead-lettered
+	DefaultRetryBaseDelay    = 1000    // milliseconds
+	DefaultRetryMaxDelay     = 3600000 // milliseconds
+	DefaultMaintenanceBatch  = 100     // delayed tasks promoted per maintenance round
+)
+
+// Conf is loaded from config/.workqueue.json
+type Conf struct {
+	KeyPrefix         string `json:"key_prefix"`         // Default: DefaultKeyPrefix
+	Queue             string `json:"queue"`              // queue name. instances with the same name share the tasks. Default: DefaultQueue
+	Concurrency       int    `json:"concurrency"`        // workers of the pool
+	PollInterval      int    `json:"poll_interval"`      // milliseconds. idle workers poll the ready list, and delayed/expired tasks are moved, at this interval
+	VisibilityTimeout int    `json:"visibility_timeout"` // milliseconds. a claimed task not acked nor extended within it goes back to the ready list
+	TaskTimeout       int    `json:"task_timeout"`       // milliseconds. 0 = no timeout
+	MaxAttempts       int    `json:"max_attempts"`       // attempts before the task is dead-lettered
+	RetryBaseDelay    int    `json:"retry_base_delay"`   // milliseconds. doubled per failed attempt
+	RetryMaxDelay     int    `json:"retry_max_delay"`    // milliseconds. upper bound of the retry delay
+}
+
+// SetDefaults fills the zero fields with the defaults
+func (c *Conf) SetDefaults() {
+	if c.KeyPrefix == "" {
+		c.KeyPrefix = DefaultKeyPrefix
+	}
+	if c.Queue == "" {
+		c.Queue = DefaultQueue
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = DefaultConcurrency
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = DefaultPollInterval
+	}
+	if c.VisibilityTimeout <= 0 {
+		c.VisibilityTimeout = DefaultVisibilityTimeout
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = DefaultMaxAttempts
+	}
+	if c.RetryBaseDelay <= 0 {
+		c.RetryBaseDelay = DefaultRetryBaseDelay
+	}
+	if c.RetryMaxDelay <= 0 {
+		c.RetryMaxDelay = DefaultRetryMaxDelay
+	}
+}
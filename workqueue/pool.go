@@ -0,0 +1,250 @@
+package workqueue
+
+import (
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/logitools/gw/svc"
+)
+
+// Handler runs a task of a type with its JSON payload. A returned error is retried until Conf.MaxAttempts
+type Handler func(ctx context.Context, payload string) error
+
+// Pool is a service running Conf.Concurrency workers on a Queue.
+// It also moves the due delayed tasks and the expired claims to the ready list every Conf.PollInterval,
+// so any number of instances can share the queue.
+type Pool struct {
+	Ctx      context.Context    // Service Context
+	cancel   context.CancelFunc // Service Context CancelFunc
+	state    int                // internal service state
+	done     chan error         // Shutdown Error Channel
+	queue    *Queue
+	handlers map[string]Handler // guarded by mu
+	mu       sync.RWMutex
+	wg       sync.WaitGroup
+}
+
+func (p *Pool) Name() string {
+	return "WorkQueuePool"
+}
+
+func NewPool(parentCtx context.Context, queue *Queue) *Pool {
+	svcCtx, svcCancel := context.WithCancel(parentCtx)
+	return &Pool{
+		Ctx:      svcCtx,
+		cancel:   svcCancel,
+		state:    svc.StateREADY,
+		done:     make(chan error, 1),
+		queue:    queue,
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Register registers the handler of a task type. Tasks of unregistered types are dead-lettered
+func (p *Pool) Register(taskType string, handler Handler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers[taskType] = handler
+}
+
+// RegisterTyped registers a task type whose JSON payload is decoded into P
+func RegisterTyped[P any](p *Pool, taskType string, handler func(ctx context.Context, payload P) error) {
+	p.Register(taskType, func(ctx context.Context, payload string) error {
+		var v P
+		if err := json.Unmarshal([]byte(payload), &v); err != nil {
+			return fmt.Errorf("task type %s: invalid payload: %w", taskType, err)
+		}
+		return handler(ctx, v)
+	})
+}
+
+func (p *Pool) handler(taskType string) (Handler, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	handler, ok := p.handlers[taskType]
+	return handler, ok
+}
+
+func (p *Pool) Start() error {
+	if p.state == svc.StateRUNNING {
+		return fmt.Errorf("already started")
+	}
+	if p.state != svc.StateREADY {
+		return fmt.Errorf("cannot start. not ready")
+	}
+	p.state = svc.StateRUNNING
+	conf := p.queue.conf
+	log.Printf("[INFO][WorkQueue] pool started queue=%s concurrency=%d", conf.Queue, conf.Concurrency)
+	for range conf.Concurrency {
+		p.wg.Add(1)
+		go p.work()
+	}
+	go p.run()
+	return nil
+}
+
+func (p *Pool) Stop() {
+	if p.state != svc.StateRUNNING {
+		log.Println("[ERROR][WorkQueue] cannot stop. not running")
+		return
+	}
+	p.cancel()
+	p.state = svc.StateSTOPPED
+	log.Println("[INFO][WorkQueue] service stopped")
+}
+
+func (p *Pool) Done() <-chan error {
+	return p.done
+}
+
+// run does the maintenance of the queue until the service stops, then waits for the workers
+func (p *Pool) run() {
+	ticker := time.NewTicker(p.pollInterval())
+	defer ticker.Stop()
+	for {
+		p.maintain()
+		select {
+		case <-p.Ctx.Done():
+			log.Println("[INFO][WorkQueue] stopping pool service")
+			p.wg.Wait()
+			p.done <- nil
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Pool) maintain() {
+	now := time.Now()
+	if _, err := p.queue.promoteDue(p.Ctx, now); err != nil {
+		log.Printf("[ERROR][WorkQueue] promote delayed tasks: %v", err)
+	}
+	if n, err := p.queue.reapExpired(p.Ctx, now); err != nil {
+		log.Printf("[ERROR][WorkQueue] reap expired tasks: %v", err)
+	} else if n > 0 {
+		log.Printf("[WARN][WorkQueue] %d tasks back to ready after their visibility timeout", n)
+	}
+}
+
+// work claims and processes the ready tasks until the service stops, polling when none is ready
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for p.Ctx.Err() == nil {
+		task, ok, err := p.queue.claim(p.Ctx)
+		if err != nil {
+			log.Printf("[ERROR][WorkQueue] claim: %v", err)
+		}
+		if task != nil {
+			p.process(task)
+			continue
+		}
+		if ok {
+			continue // dropped a broken task. the next one may be ready
+		}
+		select {
+		case <-p.Ctx.Done():
+		case <-time.After(p.pollInterval()):
+		}
+	}
+}
+
+// process runs the handler of the task and records the outcome.
+// A task interrupted by the shutdown goes back to the ready list without counting the attempt.
+func (p *Pool) process(task *Task) {
+	kvCtx := context.WithoutCancel(p.Ctx) // the outcome is recorded even on shutdown
+	handler, ok := p.handler(task.Type)
+	if !ok {
+		task.Attempts = p.queue.conf.MaxAttempts - 1 // no retry
+		p.fail(kvCtx, task, fmt.Errorf("task type %q not registered", task.Type))
+		return
+	}
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout := p.queue.conf.TaskTimeout; timeout > 0 {
+		ctx, cancel = context.WithTimeout(p.Ctx, time.Duration(timeout)*time.Millisecond)
+	} else {
+		ctx, cancel = context.WithCancel(p.Ctx)
+	}
+	defer cancel()
+	stopExtend := p.keepLease(ctx, task)
+	err := runHandler(ctx, handler, task)
+	stopExtend()
+	switch {
+	case err == nil:
+		if ackErr := p.queue.ack(kvCtx, task); errors.Is(ackErr, ErrLeaseLost) {
+			log.Printf("[WARN][WorkQueue] task %s done after its lease expired. it may run again", task.ID)
+		} else if ackErr != nil {
+			log.Printf("[ERROR][WorkQueue] ack task %s: %v", task.ID, ackErr)
+		}
+	case p.Ctx.Err() != nil:
+		if relErr := p.queue.release(kvCtx, task); relErr != nil && !errors.Is(relErr, ErrLeaseLost) {
+			log.Printf("[ERROR][WorkQueue] release task %s: %v", task.ID, relErr)
+		}
+	default:
+		p.fail(kvCtx, task, err)
+	}
+}
+
+func (p *Pool) fail(ctx context.Context, task *Task, taskErr error) {
+	dead, err := p.queue.fail(ctx, task, taskErr)
+	switch {
+	case errors.Is(err, ErrLeaseLost):
+		log.Printf("[WARN][WorkQueue] task %s (%s) failed after its lease expired: %v", task.ID, task.Type, taskErr)
+	case err != nil:
+		log.Printf("[ERROR][WorkQueue] record failure of task %s: %v", task.ID, err)
+	case dead:
+		log.Printf("[ERROR][WorkQueue] task %s (%s) dead-lettered after %d attempts: %v", task.ID, task.Type, task.Attempts, taskErr)
+	default:
+		log.Printf("[WARN][WorkQueue] task %s (%s) attempt %d failed: %v", task.ID, task.Type, task.Attempts, taskErr)
+	}
+}
+
+// keepLease extends the lease of the task every third of the visibility timeout while its handler runs
+func (p *Pool) keepLease(ctx context.Context, task *Task) (stop func()) {
+	extendCtx, cancel := context.WithCancel(ctx)
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(p.queue.visibilityTimeout() / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-extendCtx.Done():
+				return
+			case <-ticker.C:
+				extended, err := p.queue.extend(extendCtx, task)
+				if err != nil {
+					log.Printf("[ERROR][WorkQueue] extend task %s: %v", task.ID, err)
+				} else if !extended {
+					log.Printf("[WARN][WorkQueue] task %s lease expired while running. it may run again", task.ID)
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-finished
+	}
+}
+
+// runHandler converts a panic of the handler into an error
+func runHandler(ctx context.Context, handler Handler, task *Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[PANIC][WorkQueue] task %s (%s) panicked: %v\n%s", task.ID, task.Type, r, debug.Stack())
+			err = fmt.Errorf("task %s panicked: %v", task.ID, r)
+		}
+	}()
+	return handler(ctx, task.Payload)
+}
+
+func (p *Pool) pollInterval() time.Duration {
+	return time.Duration(p.queue.conf.PollInterval) * time.Millisecond
+}
@@ -0,0 +1,426 @@
+package workqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/logitools/gw/db/kvdb"
+)
+
+// Queue keeps the tasks in the KV DB, under KeyPrefix + Queue + ":"
+//   - ready: list of task ids to claim, FIFO
+//   - processing: list of claimed task ids
+//   - leases: hash of claimed task id -> "<visibility deadline (unix ms)>:<claim token>"
+//   - delayed: sorted set of task ids by the time to become ready (unix ms)
+//   - dead: list of dead-lettered task ids
+//   - tasks: hash of task id -> JSON Task
+//
+// A claimed task stays in the processing list until acked. If its lease is not extended before the deadline,
+// e.g. the worker crashed, it goes back to the ready list and runs again: handlers should be idempotent.
+// The outcome of a run is only recorded while its claim holds the lease, so a late worker cannot touch a task claimed again.
+type Queue struct {
+	client     kvdb.Client
+	conf       *Conf
+	readyKey   string
+	procKey    string
+	leasesKey  string
+	delayedKey string
+	deadKey    string
+	tasksKey   string
+}
+
+// NewQueue creates a Queue. conf is filled with the defaults
+func NewQueue(client kvdb.Client, conf *Conf) *Queue {
+	conf.SetDefaults()
+	base := conf.KeyPrefix + conf.Queue + ":"
+	return &Queue{
+		client:     client,
+		conf:       conf,
+		readyKey:   base + "ready",
+		procKey:    base + "processing",
+		leasesKey:  base + "leases",
+		delayedKey: base + "delayed",
+		deadKey:    base + "dead",
+		tasksKey:   base + "tasks",
+	}
+}
+
+func (q *Queue) Conf() *Conf {
+	return q.conf
+}
+
+// Stats are the numbers of tasks by state
+type Stats struct {
+	Ready      int64 `json:"ready"`
+	Processing int64 `json:"processing"`
+	Delayed    int64 `json:"delayed"`
+	Dead       int64 `json:"dead"`
+}
+
+// ErrLeaseLost is returned when the outcome of a task run is not recorded, since the lease of its claim expired
+var ErrLeaseLost = errors.New("lease lost")
+
+// leaseCheck starts the scripts run by the holder of a lease: returns 0 unless the claim token holds the lease.
+// KEYS[2] = leases. ARGV[1] = id, ARGV[2] = claim token
+const leaseCheck = `
+local lease = redis.call('HGET', KEYS[2], ARGV[1])
+if not lease or string.match(lease, ':(.*)$') ~= ARGV[2] then
+  return 0
+end
+`
+
+// claimScript KEYS = ready, processing, leases, tasks. ARGV = deadline (ms), claim token. Returns {id, body} or nil if empty
+const claimScript = `
+local id = redis.call('LPOP', KEYS[1])
+if not id then
+  return false
+end
+local body = redis.call('HGET', KEYS[4], id)
+if not body then
+  return {id, ''}
+end
+redis.call('RPUSH', KEYS[2], id)
+redis.call('HSET', KEYS[3], id, ARGV[1] .. ':' .. ARGV[2])
+return {id, body}
+`
+
+// ackScript KEYS = processing, leases, tasks. ARGV = id, claim token
+const ackScript = leaseCheck + `
+redis.call('LREM', KEYS[1], 1, ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+return 1
+`
+
+// retryScript KEYS = processing, leases, tasks, delayed. ARGV = id, claim token, body, ready at (ms)
+const retryScript = leaseCheck + `
+redis.call('LREM', KEYS[1], 1, ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('HSET', KEYS[3], ARGV[1], ARGV[3])
+redis.call('ZADD', KEYS[4], ARGV[4], ARGV[1])
+return 1
+`
+
+// buryScript KEYS = processing, leases, tasks, dead. ARGV = id, claim token, body
+const buryScript = leaseCheck + `
+redis.call('LREM', KEYS[1], 1, ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('HSET', KEYS[3], ARGV[1], ARGV[3])
+redis.call('RPUSH', KEYS[4], ARGV[1])
+return 1
+`
+
+// releaseScript puts a claimed task back at the head of the ready list. KEYS = processing, leases, ready. ARGV = id, claim token
+const releaseScript = leaseCheck + `
+redis.call('LREM', KEYS[1], 1, ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('LPUSH', KEYS[3], ARGV[1])
+return 1
+`
+
+// extendScript KEYS = processing, leases. ARGV = id, claim token, deadline (ms). Returns 0 if the lease expired
+const extendScript = leaseCheck + `
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[3] .. ':' .. ARGV[2])
+return 1
+`
+
+// enqueueScript KEYS = tasks, ready. ARGV = id, body
+const enqueueScript = `
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+redis.call('RPUSH', KEYS[2], ARGV[1])
+return 1
+`
+
+// delayScript KEYS = tasks, delayed. ARGV = id, body, ready at (ms)
+const delayScript = `
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+return 1
+`
+
+// promoteScript moves the due delayed tasks to the ready list. KEYS = delayed, ready. ARGV = now (ms), limit
+const promoteScript = `
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, id in ipairs(ids) do
+  redis.call('ZREM', KEYS[1], id)
+  redis.call('RPUSH', KEYS[2], id)
+end
+return #ids
+`
+
+// reapScript moves the claimed tasks past their deadline back to the ready list. KEYS = processing, leases, ready. ARGV = now (ms)
+const reapScript = `
+local leases = redis.call('HGETALL', KEYS[2])
+local n = 0
+for i = 1, #leases, 2 do
+  if tonumber(string.match(leases[i + 1], '^(%d+)')) <= tonumber(ARGV[1]) then
+    redis.call('LREM', KEYS[1], 1, leases[i])
+    redis.call('HDEL', KEYS[2], leases[i])
+    redis.call('RPUSH', KEYS[3], leases[i])
+    n = n + 1
+  end
+end
+return n
+`
+
+// requeueDeadScript KEYS = dead, tasks, ready. ARGV = id, body. Returns 0 if not dead-lettered
+const requeueDeadScript = `
+if redis.call('LREM', KEYS[1], 1, ARGV[1]) == 0 then
+  return 0
+end
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[2])
+redis.call('RPUSH', KEYS[3], ARGV[1])
+return 1
+`
+
+// purgeDeadScript KEYS = dead, tasks. Returns the number of purged tasks
+const purgeDeadScript = `
+local ids = redis.call('LRANGE', KEYS[1], 0, -1)
+for _, id in ipairs(ids) do
+  redis.call('HDEL', KEYS[2], id)
+end
+redis.call('DEL', KEYS[1])
+return #ids
+`
+
+// statsScript KEYS = ready, processing, delayed, dead
+const statsScript = `
+return {redis.call('LLEN', KEYS[1]), redis.call('LLEN', KEYS[2]), redis.call('ZCARD', KEYS[3]), redis.call('LLEN', KEYS[4])}
+`
+
+// Enqueue adds a task of the handler type with the payload marshaled to JSON. Returns the task id
+func (q *Queue) Enqueue(ctx context.Context, taskType string, payload any) (string, error) {
+	task, body, err := newTask(taskType, payload)
+	if err != nil {
+		return "", err
+	}
+	if _, err = q.client.Eval(ctx, enqueueScript, []string{q.tasksKey, q.readyKey}, task.ID, body); err != nil {
+		return "", err
+	}
+	return task.ID, nil
+}
+
+// EnqueueIn adds a task to become ready after delay
+func (q *Queue) EnqueueIn(ctx context.Context, taskType string, payload any, delay time.Duration) (string, error) {
+	return q.EnqueueAt(ctx, taskType, payload, time.Now().Add(delay))
+}
+
+// EnqueueAt adds a task to become ready at readyAt, within Conf.PollInterval
+func (q *Queue) EnqueueAt(ctx context.Context, taskType string, payload any, readyAt time.Time) (string, error) {
+	task, body, err := newTask(taskType, payload)
+	if err != nil {
+		return "", err
+	}
+	if _, err = q.client.Eval(ctx, delayScript, []string{q.tasksKey, q.delayedKey}, task.ID, body, readyAt.UnixMilli()); err != nil {
+		return "", err
+	}
+	return task.ID, nil
+}
+
+func newTask(taskType string, payload any) (*Task, string, error) {
+	if taskType == "" {
+		return nil, "", errors.New("task type required")
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, "", fmt.Errorf("task type %s: invalid payload: %w", taskType, err)
+	}
+	task := &Task{ID: rand.Text(), Type: taskType, Payload: string(payloadBytes), CreatedAt: time.Now()}
+	body, err := json.Marshal(task)
+	if err != nil {
+		return nil, "", err
+	}
+	return task, string(body), nil
+}
+
+// claim takes the next ready task with a lease of Conf.VisibilityTimeout. ok = false if none is ready
+func (q *Queue) claim(ctx context.Context) (*Task, bool, error) {
+	deadline := time.Now().Add(q.visibilityTimeout()).UnixMilli()
+	token := rand.Text()
+	res, err := q.client.Eval(ctx, claimScript, []string{q.readyKey, q.procKey, q.leasesKey, q.tasksKey}, deadline, token)
+	if errors.Is(err, kvdb.ErrNil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	reply, ok := res.([]any)
+	if !ok || len(reply) != 2 {
+		return nil, false, fmt.Errorf("unexpected script reply: %v", res)
+	}
+	id, _ := reply[0].(string)
+	body, _ := reply[1].(string)
+	if body == "" {
+		return nil, true, fmt.Errorf("task %s dropped: body not found", id) // ok: try the next one at once
+	}
+	var task Task
+	if err = json.Unmarshal([]byte(body), &task); err != nil {
+		_, _ = q.client.Eval(ctx, buryScript, []string{q.procKey, q.leasesKey, q.tasksKey, q.deadKey}, id, token, body)
+		return nil, true, fmt.Errorf("task %s dead-lettered: invalid body: %w", id, err)
+	}
+	task.claimToken = token
+	return &task, true, nil
+}
+
+// ack removes a finished task. ErrLeaseLost if the task was claimed again
+func (q *Queue) ack(ctx context.Context, task *Task) error {
+	return q.evalLeased(ctx, ackScript, []string{q.procKey, q.leasesKey, q.tasksKey}, task)
+}
+
+// evalLeased runs a script starting with leaseCheck. ErrLeaseLost if the claim of the task no longer holds the lease
+func (q *Queue) evalLeased(ctx context.Context, script string, keys []string, task *Task, args ...any) error {
+	res, err := q.client.Eval(ctx, script, keys, append([]any{task.ID, task.claimToken}, args...)...)
+	if err != nil {
+		return err
+	}
+	if done, _ := res.(int64); done != 1 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// fail records a failed attempt, delaying the task for a retry or dead-lettering it after Conf.MaxAttempts.
+// Returns true if dead-lettered. ErrLeaseLost if the task was claimed again
+func (q *Queue) fail(ctx context.Context, task *Task, taskErr error) (bool, error) {
+	task.Attempts++
+	task.LastError = taskErr.Error()
+	body, err := json.Marshal(task)
+	if err != nil {
+		return false, err
+	}
+	if task.Attempts >= q.conf.MaxAttempts {
+		err = q.evalLeased(ctx, buryScript, []string{q.procKey, q.leasesKey, q.tasksKey, q.deadKey}, task, string(body))
+		return err == nil, err
+	}
+	readyAt := time.Now().Add(q.retryDelay(task.Attempts)).UnixMilli()
+	err = q.evalLeased(ctx, retryScript, []string{q.procKey, q.leasesKey, q.tasksKey, q.delayedKey}, task, string(body), readyAt)
+	return false, err
+}
+
+// release puts a claimed task back to the head of the ready list without counting an attempt, e.g. on shutdown.
+// ErrLeaseLost if the task was claimed again
+func (q *Queue) release(ctx context.Context, task *Task) error {
+	return q.evalLeased(ctx, releaseScript, []string{q.procKey, q.leasesKey, q.readyKey}, task)
+}
+
+// extend renews the lease of a claimed task. Returns false if it already expired and the task went back to the ready list
+func (q *Queue) extend(ctx context.Context, task *Task) (bool, error) {
+	deadline := time.Now().Add(q.visibilityTimeout()).UnixMilli()
+	err := q.evalLeased(ctx, extendScript, []string{q.procKey, q.leasesKey}, task, deadline)
+	if errors.Is(err, ErrLeaseLost) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// promoteDue moves the delayed tasks due at now to the ready list. Returns the number moved
+func (q *Queue) promoteDue(ctx context.Context, now time.Time) (int64, error) {
+	res, err := q.client.Eval(ctx, promoteScript, []string{q.delayedKey, q.readyKey}, now.UnixMilli(), DefaultMaintenanceBatch)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.(int64)
+	return n, nil
+}
+
+// reapExpired moves the claimed tasks whose lease expired at now back to the ready list. Returns the number moved
+func (q *Queue) reapExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := q.client.Eval(ctx, reapScript, []string{q.procKey, q.leasesKey, q.readyKey}, now.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.(int64)
+	return n, nil
+}
+
+func (q *Queue) visibilityTimeout() time.Duration {
+	return time.Duration(q.conf.VisibilityTimeout) * time.Millisecond
+}
+
+// retryDelay returns RetryBaseDelay doubled per failed attempt, capped by RetryMaxDelay
+func (q *Queue) retryDelay(attempts int) time.Duration {
+	delay := time.Duration(q.conf.RetryBaseDelay) * time.Millisecond << min(attempts-1, 30)
+	if maxDelay := time.Duration(q.conf.RetryMaxDelay) * time.Millisecond; delay <= 0 || delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+func (q *Queue) Stats(ctx context.Context) (Stats, error) {
+	res, err := q.client.Eval(ctx, statsScript, []string{q.readyKey, q.procKey, q.delayedKey, q.deadKey})
+	if err != nil {
+		return Stats{}, err
+	}
+	reply, ok := res.([]any)
+	if !ok || len(reply) != 4 {
+		return Stats{}, fmt.Errorf("unexpected script reply: %v", res)
+	}
+	counts := make([]int64, 4)
+	for i, v := range reply {
+		counts[i], _ = v.(int64)
+	}
+	return Stats{Ready: counts[0], Processing: counts[1], Delayed: counts[2], Dead: counts[3]}, nil
+}
+
+// DeadTasks returns up to limit dead-lettered tasks, oldest first
+func (q *Queue) DeadTasks(ctx context.Context, limit int) ([]*Task, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	ids, err := q.client.Range(ctx, q.deadKey, 0, int64(limit-1))
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	bodies, err := q.client.GetFields(ctx, q.tasksKey, ids...)
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([]*Task, 0, len(ids))
+	for _, id := range ids {
+		task := &Task{ID: id}
+		if body, ok := bodies[id]; ok {
+			if err = json.Unmarshal([]byte(body), task); err != nil {
+				task.LastError = "invalid body: " + body
+			}
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// RequeueDead moves a dead-lettered task back to the ready list with its attempts reset. Returns false if not found
+func (q *Queue) RequeueDead(ctx context.Context, id string) (bool, error) {
+	body, found, err := q.client.GetField(ctx, q.tasksKey, id)
+	if err != nil || !found {
+		return false, err
+	}
+	var task Task
+	if err = json.Unmarshal([]byte(body), &task); err != nil {
+		return false, fmt.Errorf("task %s: invalid body: %w", id, err)
+	}
+	task.Attempts = 0
+	newBody, err := json.Marshal(&task)
+	if err != nil {
+		return false, err
+	}
+	res, err := q.client.Eval(ctx, requeueDeadScript, []string{q.deadKey, q.tasksKey, q.readyKey}, id, string(newBody))
+	if err != nil {
+		return false, err
+	}
+	requeued, _ := res.(int64)
+	return requeued == 1, nil
+}
+
+// PurgeDead deletes all the dead-lettered tasks. Returns the number deleted
+func (q *Queue) PurgeDead(ctx context.Context) (int64, error) {
+	res, err := q.client.Eval(ctx, purgeDeadScript, []string{q.deadKey, q.tasksKey})
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.(int64)
+	return n, nil
+}
@@ -0,0 +1,14 @@
+package workqueue
+
+import "time"
+
+type Task struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`    // registered handler name
+	Payload   string    `json:"payload"` // JSON. passed to the handler
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitzero"` // error of the last failed attempt
+	CreatedAt time.Time `json:"created_at"`
+
+	claimToken string // token of the claim holding the lease. set by the worker claiming it
+}